@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/api/auth/approle"
@@ -12,19 +17,29 @@ import (
 	"github.com/soerenschneider/acmevault/internal/config"
 	"github.com/soerenschneider/acmevault/internal/server"
 	"github.com/soerenschneider/acmevault/internal/server/acme"
+	"github.com/soerenschneider/acmevault/pkg/certstorage/filesystem"
+	"github.com/soerenschneider/acmevault/pkg/certstorage/k8s"
 	"github.com/soerenschneider/acmevault/pkg/certstorage/vault"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
 	vaultAuthToken      = "token"
 	vaultAuthApprole    = "approle"
 	vaultAuthKubernetes = "kubernetes"
+	vaultAuthCert       = "cert"
+	vaultAuthJwt        = "jwt"
+	vaultAuthAws        = "aws"
 	vaultAuthImplicit   = "implicit"
 )
 
 type deps struct {
+	vaultClient       *api.Client
 	vaultAuth         api.AuthMethod
 	vaultTokenRenewer *vault.TokenRenewer
+	vaultTokenWatcher *vault.TokenFileWatcher
 
 	storage             Storage
 	credentialsProvider aws.CredentialsProvider
@@ -35,17 +50,62 @@ type Storage interface {
 	server.CertStorage
 	acme.AccountStorage
 	acme.AwsDynamicCredentialsBackend
+	acme.AwsRoleCredentialsBackend
+	acme.SecretBackend
 }
 
 func buildDeps(conf config.AcmeVaultConfig) *deps {
 	deps := &deps{}
 	var err error
 
+	switch conf.Storage {
+	case config.StorageFilesystem:
+		deps.storage, err = filesystem.NewFilesystemBackend(*conf.Filesystem)
+		dieOnError(err, "could not generate desired backend")
+	case config.StorageKubernetes:
+		client, err := buildKubernetesClient(*conf.Kubernetes)
+		dieOnError(err, "could not build kubernetes client")
+		deps.storage, err = k8s.NewKubernetesBackend(client, *conf.Kubernetes)
+		dieOnError(err, "could not generate desired backend")
+	default:
+		buildVaultDeps(conf, deps)
+	}
+
+	deps.credentialsProvider, err = acme.NewAwsDynamicCredentialsProvider(deps.storage)
+	dieOnError(err, "could not build dynamic credentials provider")
+
+	dnsProviderDeps := acme.DnsProviderDeps{
+		AwsCredentials:     deps.credentialsProvider,
+		AwsRoleCredentials: deps.storage,
+		Secrets:            deps.storage,
+	}
+	deps.dnsProvider, err = acme.BuildDnsProviders(conf, dnsProviderDeps)
+	dieOnError(err, "could not build dns provider")
+
+	return deps
+}
+
+func buildVaultDeps(conf config.AcmeVaultConfig, deps *deps) {
 	vaultClient, err := buildVaultClient(conf.Vault)
 	dieOnError(err, "could not build vault client")
+	deps.vaultClient = vaultClient
+
+	// the agent takes care of authentication, acmevault must neither login nor renew or revoke the token
+	if !conf.Vault.UseAgent() {
+		deps.vaultAuth, err = buildVaultAuth(conf.Vault, vaultClient)
+		dieOnError(err, "could not build token auth")
 
-	deps.vaultAuth, err = buildVaultAuth(conf.Vault)
-	dieOnError(err, "could not build token auth")
+		// nothing ever logs in again using implicit auth, the token is swapped whenever a token file changes instead
+		if implicitAuth, ok := deps.vaultAuth.(*vault.ImplicitAuth); ok {
+			deps.vaultTokenWatcher, err = vault.NewTokenFileWatcher(vaultClient, implicitAuth)
+			dieOnError(err, "could not build token file watcher")
+		}
+
+		if conf.Vault.GetAuthNamespace() != conf.Vault.Namespace {
+			deps.vaultAuth, err = vault.NewNamespacedAuth(deps.vaultAuth, conf.Vault.GetAuthNamespace())
+			dieOnError(err, "could not build namespaced auth")
+		}
+	}
 
 	if conf.Vault.UseAutoRenewAuth() {
 		log.Info().Msg("Building Vault auth auto renew wrapper...")
@@ -55,14 +115,6 @@ func buildDeps(conf config.AcmeVaultConfig) *deps {
 
 	deps.storage, err = vault.NewVaultBackend(vaultClient, conf.Vault)
 	dieOnError(err, "could not generate desired backend")
-
-	deps.credentialsProvider, err = acme.NewAwsDynamicCredentialsProvider(deps.storage)
-	dieOnError(err, "could not build dynamic credentials provider")
-
-	deps.dnsProvider, err = acme.BuildRoute53DnsProvider(deps.credentialsProvider)
-	dieOnError(err, "could not build dns provider")
-
-	return deps
 }
 
 func dieOnError(err error, msg string) {
@@ -73,16 +125,71 @@ func dieOnError(err error, msg string) {
 
 func buildVaultClient(conf config.VaultConfig) (*api.Client, error) {
 	vaultConf := api.DefaultConfig()
-	vaultConf.Address = conf.Addr
+	vaultConf.Address = conf.GetAddr()
 	vaultConf.MaxRetries = 3
-	return api.NewClient(vaultConf)
+
+	tlsConf := &api.TLSConfig{
+		CACert:        conf.CaCert,
+		CAPath:        conf.CaPath,
+		TLSServerName: conf.TlsServerName,
+		Insecure:      conf.InsecureSkipVerify,
+	}
+	if err := vaultConf.ConfigureTLS(tlsConf); err != nil {
+		return nil, fmt.Errorf("could not configure tls: %w", err)
+	}
+
+	if conf.UseClientCert() {
+		reloader, err := vault.NewClientCertReloader(conf.ClientCert, conf.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		transport, ok := vaultConf.HttpClient.Transport.(*http.Transport)
+		if !ok {
+			return nil, errors.New("unexpected http transport of vault client")
+		}
+		transport.TLSClientConfig.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	client, err := api.NewClient(vaultConf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conf.Namespace) > 0 {
+		client.SetNamespace(conf.Namespace)
+	}
+
+	// requests must not carry a token, e.g. from VAULT_TOKEN, so the agent uses its auto-auth token
+	if conf.UseAgent() {
+		client.ClearToken()
+	}
+
+	return client, nil
 }
 
-func buildVaultAuth(conf config.VaultConfig) (api.AuthMethod, error) {
+func buildKubernetesClient(conf config.KubernetesConfig) (k8sclient.Interface, error) {
+	var restConf *rest.Config
+	var err error
+	if len(conf.Kubeconfig) > 0 {
+		restConf, err = clientcmd.BuildConfigFromFlags("", conf.Kubeconfig)
+	} else {
+		restConf, err = rest.InClusterConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return k8sclient.NewForConfig(restConf)
+}
+
+func buildVaultAuth(conf config.VaultConfig, client *api.Client) (api.AuthMethod, error) {
 	switch conf.AuthMethod {
 	case vaultAuthToken:
 		return vault.NewTokenAuth(conf.Token)
 	case vaultAuthApprole:
+		if conf.UseWrappedSecretId() {
+			return buildWrappedAppRoleAuth(conf, client)
+		}
 		secretId := &approle.SecretID{
 			FromFile:   conf.SecretIdFile,
 			FromString: conf.SecretId,
@@ -93,9 +200,33 @@ func buildVaultAuth(conf config.VaultConfig) (api.AuthMethod, error) {
 			kubernetes.WithMountPath(conf.K8sMountPath),
 		}
 		return kubernetes.NewKubernetesAuth(conf.K8sRoleId, opts...)
+	case vaultAuthCert:
+		return vault.NewCertAuth(conf.CertMountPath, conf.CertRole, conf.CertClientCert, conf.CertClientKey)
+	case vaultAuthJwt:
+		return vault.NewJwtAuth(conf.JwtMountPath, conf.JwtRole, conf.JwtFile)
+	case vaultAuthAws:
+		awsConf, err := awsconfig.LoadDefaultConfig(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("could not load aws config: %w", err)
+		}
+		return vault.NewAwsIamAuth(conf.AwsAuthMountPath, conf.AwsAuthRole, conf.AwsAuthServerId, conf.AwsAuthRegion, awsConf.Credentials)
 	case vaultAuthImplicit:
-		return vault.NewImplicitAuth()
+		return vault.NewImplicitAuth(conf.ImplicitTokenFiles...)
 	default:
 		return nil, fmt.Errorf("no valid auth method: %s", conf.AuthMethod)
 	}
 }
+
+func buildWrappedAppRoleAuth(conf config.VaultConfig, client *api.Client) (api.AuthMethod, error) {
+	wrappingToken, err := conf.GetSecretIdWrappingToken()
+	if err != nil {
+		return nil, fmt.Errorf("could not read wrapping token: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// the wrapping token has been issued in the namespace of the auth mount
+	log.Info().Msg("Unwrapping AppRole secret id...")
+	return vault.NewWrappedAppRoleAuth(ctx, client.WithNamespace(conf.GetAuthNamespace()), conf.RoleId, wrappingToken)
+}
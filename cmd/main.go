@@ -21,13 +21,14 @@ import (
 	"github.com/soerenschneider/acmevault/internal/metrics"
 	"github.com/soerenschneider/acmevault/internal/server"
 	"github.com/soerenschneider/acmevault/internal/server/acme"
+	"github.com/soerenschneider/acmevault/pkg/certstorage/vault"
 	"golang.org/x/term"
 )
 
 func main() {
-	configPath := parseCli()
+	args := parseCli()
 	log.Info().Msgf("acmevault-server version %s, commit %s", internal.BuildVersion, internal.CommitHash)
-	conf, err := config.GetConfig(configPath)
+	conf, err := config.GetConfig(args.configFile)
 	if err != nil {
 		log.Fatal().Err(err).Msgf("Could not load config")
 	}
@@ -38,34 +39,87 @@ func main() {
 	setupLogLevel(conf.Verbose)
 
 	deps := buildDeps(conf)
-	run(conf, deps)
+	switch {
+	case args.command == cmdRevoke:
+		revoke(conf, deps, args)
+	case args.command == cmdPlan:
+		plan(conf, deps)
+	case args.once:
+		runOnce(conf, deps)
+	default:
+		run(conf, deps)
+	}
 }
 
 const (
 	envConfFile = "ACMEVAULT_CONFIG_FILE"
 	cliConfFile = "config"
 	cliVersion  = "version"
+	cliDomain   = "domain"
+	cliReason   = "reason"
+	cliOnce     = "once"
+
+	cmdRun    = "run"
+	cmdRevoke = "revoke"
+	cmdPlan   = "plan"
 )
 
-func parseCli() (configFile string) {
-	flag.StringVar(&configFile, cliConfFile, os.Getenv(envConfFile), "path to the config file")
-	version := flag.Bool(cliVersion, false, "Print version and exit")
-	flag.Parse()
+type cliArgs struct {
+	command    string
+	configFile string
+
+	// once checks all certs a single time instead of running continuously
+	once bool
+
+	// domain and reason are used by the revoke command
+	domain string
+	reason string
+}
+
+func parseCli() cliArgs {
+	args := cliArgs{command: cmdRun}
+	osArgs := os.Args[1:]
+	if len(osArgs) > 0 && !strings.HasPrefix(osArgs[0], "-") {
+		args.command = osArgs[0]
+		osArgs = osArgs[1:]
+	}
+
+	flags := flag.NewFlagSet(args.command, flag.ExitOnError)
+	flags.StringVar(&args.configFile, cliConfFile, os.Getenv(envConfFile), "path to the config file")
+	version := flags.Bool(cliVersion, false, "Print version and exit")
+
+	switch args.command {
+	case cmdRun:
+		flags.BoolVar(&args.once, cliOnce, false, "Check all certificates once, print the results and exit")
+	case cmdPlan:
+	case cmdRevoke:
+		flags.StringVar(&args.domain, cliDomain, "", "domain of the certificate to revoke")
+		flags.StringVar(&args.reason, cliReason, "unspecified", "RFC 5280 revocation reason, e.g. keyCompromise")
+	default:
+		log.Fatal().Msgf("Unknown command '%s', use '%s', '%s' or '%s'", args.command, cmdRun, cmdPlan, cmdRevoke)
+	}
+
+	// flag.ExitOnError exits on errors
+	_ = flags.Parse(osArgs)
 
 	if *version {
 		fmt.Printf("%s (revision %s)", internal.BuildVersion, internal.CommitHash)
 		os.Exit(0)
 	}
 
-	if len(configFile) == 0 {
+	if len(args.configFile) == 0 {
 		log.Fatal().Msgf("No config file specified, use flag '-%s' or env var '%s'", cliConfFile, envConfFile)
 	}
 
-	if strings.HasPrefix(configFile, "~/") {
-		configFile = path.Join(getUserHomeDirectory(), configFile[2:])
+	if strings.HasPrefix(args.configFile, "~/") {
+		args.configFile = path.Join(getUserHomeDirectory(), args.configFile[2:])
+	}
+
+	if args.command == cmdRevoke && len(args.domain) == 0 {
+		log.Fatal().Msgf("No domain specified, use flag '-%s'", cliDomain)
 	}
 
-	return
+	return args
 }
 
 func getUserHomeDirectory() string {
@@ -95,10 +149,83 @@ func run(conf config.AcmeVaultConfig, deps *deps) {
 
 	wg := &sync.WaitGroup{}
 
+	acmeVault := buildServer(ctx, conf, deps)
+
+	// the certs are checked in the background, so signals are handled while a check is running
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		checkCerts(ctx, acmeVault, wg)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				checkCerts(ctx, acmeVault, wg)
+			}
+		}
+	}()
+
+	<-done
+	log.Info().Msg("Received signal, quitting")
+	if err := deps.storage.Logout(); err != nil {
+		log.Warn().Err(err).Msg("Logging out failed")
+	}
+	cancel()
+	ticker.Stop()
+
+	log.Info().Msg("Waiting on other components")
+	wg.Wait()
+	log.Info().Msg("Done, bye!")
+}
+
+func checkCerts(ctx context.Context, acmeVault *server.AcmeVault, wg *sync.WaitGroup) {
+	if _, err := acmeVault.CheckCerts(ctx, wg); err != nil {
+		log.Error().Err(err).Msg("error checking certs")
+	}
+}
+
+// buildServer waits for the login to vault and builds the server afterwards.
+func buildServer(ctx context.Context, conf config.AcmeVaultConfig, deps *deps) *server.AcmeVault {
+	waitForVaultLogin(ctx, deps)
+
+	acmeClient, err := acme.NewGoLegoDealer(deps.storage, conf, deps.dnsProvider, deps.storage)
+	dieOnError(err, "Could not initialize acme client")
+
+	acmeVault, err := server.New(conf.Domains, acmeClient, deps.storage)
+	dieOnError(err, "Couldn't build server")
+
+	return acmeVault
+}
+
+func waitForVaultLogin(ctx context.Context, deps *deps) {
+	if deps.vaultTokenWatcher != nil {
+		err := deps.vaultTokenWatcher.Start(ctx)
+		dieOnError(err, "could not start watching vault token files")
+		return
+	}
+
+	if deps.vaultTokenRenewer == nil {
+		// a static token is neither renewed nor replaced, it only needs to be applied to the client once. There's
+		// nothing to do when talking to an agent, as it adds its own token.
+		if deps.vaultAuth != nil {
+			_, err := deps.vaultClient.Auth().Login(ctx, deps.vaultAuth)
+			dieOnError(err, "could not apply vault token")
+		}
+		return
+	}
+
 	appFatalErrors := make(chan error, 1)
 	vaultAuthReady := &sync.WaitGroup{}
 	vaultAuthReady.Add(1)
 	go deps.vaultTokenRenewer.StartTokenRenewal(ctx, vaultAuthReady, appFatalErrors)
+	go func() {
+		for err := range appFatalErrors {
+			if errors.Is(err, vault.ErrSecretIdConsumed) {
+				dieOnError(err, "could not log in to vault anymore")
+			}
+		}
+	}()
 
 	vaultLoginWait := make(chan struct{})
 	go func() {
@@ -113,39 +240,6 @@ func run(conf config.AcmeVaultConfig, deps *deps) {
 	case <-time.After(60 * time.Second):
 		log.Fatal().Err(errors.New("vault login exceeded timeout"))
 	}
-
-	acmeClient, err := acme.NewGoLegoDealer(deps.storage, conf, deps.dnsProvider)
-	dieOnError(err, "Could not initialize acme client")
-
-	acmeVault, err := server.New(conf.Domains, acmeClient, deps.storage)
-	dieOnError(err, "Couldn't build server")
-
-	if err := acmeVault.CheckCerts(ctx, wg); err != nil {
-		log.Error().Err(err).Msg("error checking certs")
-	}
-
-	stop := false
-	for !stop {
-		select {
-		case <-ticker.C:
-			err := acmeVault.CheckCerts(ctx, wg)
-			if err != nil {
-				log.Error().Err(err).Msg("error checking certs")
-			}
-		case <-done:
-			log.Info().Msg("Received signal, quitting")
-			if err := deps.storage.Logout(); err != nil {
-				log.Warn().Err(err).Msg("Logging out failed")
-			}
-			cancel()
-			ticker.Stop()
-			stop = true
-		}
-	}
-
-	log.Info().Msg("Waiting on other components")
-	wg.Wait()
-	log.Info().Msg("Done, bye!")
 }
 
 func setupLogLevel(debug bool) {
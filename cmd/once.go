@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+// runOnce checks all certs a single time, prints the result of each domain as JSON to stdout and exits. The exit code
+// is non-zero if any domain failed.
+func runOnce(conf config.AcmeVaultConfig, deps *deps) {
+	ctx, cancel := context.WithCancel(context.Background())
+	acmeVault := buildServer(ctx, conf, deps)
+
+	wg := &sync.WaitGroup{}
+	results, checkErr := acmeVault.CheckCerts(ctx, wg)
+	wg.Wait()
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			log.Error().Err(err).Msg("Could not write result")
+		}
+	}
+
+	if err := deps.storage.Logout(); err != nil {
+		log.Warn().Err(err).Msg("Logging out failed")
+	}
+	cancel()
+
+	if checkErr != nil {
+		log.Error().Err(checkErr).Msg("error checking certs")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
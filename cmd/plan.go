@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/internal/server"
+)
+
+// plan prints the actions that would be taken for the configured domains without contacting the CA. The exit code is
+// non-zero if the stored cert of any domain could not be evaluated.
+func plan(conf config.AcmeVaultConfig, deps *deps) {
+	ctx, cancel := context.WithCancel(context.Background())
+	waitForVaultLogin(ctx, deps)
+
+	results := server.Plan(conf.Domains, deps.storage)
+	if err := deps.storage.Logout(); err != nil {
+		log.Warn().Err(err).Msg("Logging out failed")
+	}
+	cancel()
+
+	failed := false
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "DOMAIN\tACTION\tREASON")
+	for _, result := range results {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", result.Domain, result.Action, result.Reason)
+		if result.Action == server.PlanError {
+			failed = true
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		log.Error().Err(err).Msg("Could not write plan")
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/internal/server/acme"
+)
+
+func revoke(conf config.AcmeVaultConfig, deps *deps, args cliArgs) {
+	reason, err := acme.ParseRevocationReason(args.reason)
+	dieOnError(err, "Invalid revocation reason")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	acmeVault := buildServer(ctx, conf, deps)
+	err = acmeVault.RevokeCert(args.domain, reason)
+	if logoutErr := deps.storage.Logout(); logoutErr != nil {
+		log.Warn().Err(logoutErr).Msg("Logging out failed")
+	}
+	dieOnError(err, "Could not revoke cert")
+
+	log.Info().Str("domain", args.domain).Msg("Revoked cert and obtained a new one")
+}
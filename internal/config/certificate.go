@@ -0,0 +1,41 @@
+package config
+
+const (
+	KeyTypeEc256   = "ec256"
+	KeyTypeEc384   = "ec384"
+	KeyTypeRsa2048 = "rsa2048"
+	KeyTypeRsa3072 = "rsa3072"
+	KeyTypeRsa4096 = "rsa4096"
+
+	defaultKeyType = KeyTypeRsa4096
+
+	KeyPolicyRotate      = "rotate"
+	KeyPolicyReuse       = "reuse"
+	KeyPolicyRotateEvery = "rotate-every"
+)
+
+// ReuseKey returns whether the private key should be reused for the next renewal, given the number of renewals the
+// current key has already been used for.
+func (a DomainsConfig) ReuseKey(keyRenewals int) bool {
+	switch a.KeyPolicy {
+	case KeyPolicyReuse:
+		return true
+	case KeyPolicyRotateEvery:
+		return keyRenewals+1 < a.KeyRotateEvery
+	}
+
+	return false
+}
+
+// setDomainDefaults populates the per-domain settings that have not been set explicitly with the global defaults.
+func (conf *AcmeVaultConfig) setDomainDefaults() {
+	for i := range conf.Domains {
+		if len(conf.Domains[i].KeyType) == 0 {
+			conf.Domains[i].KeyType = conf.KeyType
+		}
+		if conf.Domains[i].Renewal == nil {
+			renewal := conf.Renewal
+			conf.Domains[i].Renewal = &renewal
+		}
+	}
+}
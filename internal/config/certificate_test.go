@@ -0,0 +1,92 @@
+package config
+
+import "testing"
+
+func TestDomainsConfig_ReuseKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		domain      DomainsConfig
+		keyRenewals int
+		want        bool
+	}{
+		{
+			name:   "default",
+			domain: DomainsConfig{},
+			want:   false,
+		},
+		{
+			name:   "rotate",
+			domain: DomainsConfig{KeyPolicy: KeyPolicyRotate},
+			want:   false,
+		},
+		{
+			name:        "reuse",
+			domain:      DomainsConfig{KeyPolicy: KeyPolicyReuse},
+			keyRenewals: 10,
+			want:        true,
+		},
+		{
+			name:        "rotate-every, reuse",
+			domain:      DomainsConfig{KeyPolicy: KeyPolicyRotateEvery, KeyRotateEvery: 3},
+			keyRenewals: 1,
+			want:        true,
+		},
+		{
+			name:        "rotate-every, rotate",
+			domain:      DomainsConfig{KeyPolicy: KeyPolicyRotateEvery, KeyRotateEvery: 3},
+			keyRenewals: 2,
+			want:        false,
+		},
+		{
+			name:   "rotate-every 1",
+			domain: DomainsConfig{KeyPolicy: KeyPolicyRotateEvery, KeyRotateEvery: 1},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.domain.ReuseKey(tt.keyRenewals); got != tt.want {
+				t.Errorf("ReuseKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDomainsConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		domain  DomainsConfig
+		wantErr bool
+	}{
+		{
+			name:   "ecdsa key type",
+			domain: DomainsConfig{Domain: "valid.domain", KeyType: KeyTypeEc384},
+		},
+		{
+			name:    "invalid key type",
+			domain:  DomainsConfig{Domain: "valid.domain", KeyType: "rsa1024"},
+			wantErr: true,
+		},
+		{
+			name:   "key policy rotate-every",
+			domain: DomainsConfig{Domain: "valid.domain", KeyPolicy: KeyPolicyRotateEvery, KeyRotateEvery: 4},
+		},
+		{
+			name:    "key policy rotate-every without count",
+			domain:  DomainsConfig{Domain: "valid.domain", KeyPolicy: KeyPolicyRotateEvery},
+			wantErr: true,
+		},
+		{
+			name:    "unknown key policy",
+			domain:  DomainsConfig{Domain: "valid.domain", KeyPolicy: "never"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.domain); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
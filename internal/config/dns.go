@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/multierr"
+)
+
+const (
+	DnsProviderRoute53    = "route53"
+	DnsProviderRfc2136    = "rfc2136"
+	DnsProviderCloudflare = "cloudflare"
+	DnsProviderHetzner    = "hetzner"
+	DnsProviderPowerDns   = "pdns"
+)
+
+// dnsProviderTypes contains the DNS provider types that are accepted by the config validation. The types are not
+// known to this package, they are added by the acme package using RegisterDnsProviderType when registering a provider.
+var dnsProviderTypes []string
+
+func init() {
+	_ = validate.RegisterValidation("dns_provider_type", func(fl validator.FieldLevel) bool {
+		return IsDnsProviderType(fl.Field().String())
+	})
+}
+
+// RegisterDnsProviderType makes a DNS provider type known to the config validation.
+func RegisterDnsProviderType(name string) {
+	if !IsDnsProviderType(name) {
+		dnsProviderTypes = append(dnsProviderTypes, name)
+	}
+}
+
+// UnregisterDnsProviderType removes a DNS provider type from the config validation.
+func UnregisterDnsProviderType(name string) {
+	dnsProviderTypes = slices.DeleteFunc(dnsProviderTypes, func(registered string) bool { return registered == name })
+}
+
+// IsDnsProviderType returns whether a DNS provider of the given type is available.
+func IsDnsProviderType(name string) bool {
+	return slices.Contains(dnsProviderTypes, name)
+}
+
+// DnsProviderConfig configures a named instance of a DNS provider that can be referenced by domains.
+type DnsProviderConfig struct {
+	Name       string            `yaml:"name" validate:"required,excludesall=/ "`
+	Type       string            `yaml:"type" validate:"required,dns_provider_type"`
+	Route53    *Route53Config    `yaml:"route53,omitempty"`
+	Rfc2136    *Rfc2136Config    `yaml:"rfc2136,omitempty" validate:"required_if=Type rfc2136"`
+	Cloudflare *CloudflareConfig `yaml:"cloudflare,omitempty" validate:"required_if=Type cloudflare"`
+	Hetzner    *HetznerConfig    `yaml:"hetzner,omitempty" validate:"required_if=Type hetzner"`
+	PowerDns   *PowerDnsConfig   `yaml:"pdns,omitempty" validate:"required_if=Type pdns"`
+}
+
+func (conf DnsProviderConfig) String() string {
+	if conf.Name == conf.Type {
+		return conf.Name
+	}
+	return fmt.Sprintf("%s (%s)", conf.Name, conf.Type)
+}
+
+type Route53Config struct {
+	HostedZoneId string `yaml:"hostedZoneId" env:"HOSTED_ZONE_ID"`
+	// AwsRole overrides the role of the AWS secrets engine that is used to generate credentials for this provider.
+	AwsRole      string `yaml:"awsRole" env:"AWS_ROLE"`
+	AwsMountPath string `yaml:"awsMountPath" env:"AWS_MOUNT" validate:"omitempty,endsnotwith=/,startsnotwith=/"`
+}
+
+type Rfc2136Config struct {
+	Nameserver    string `yaml:"nameserver" validate:"required"`
+	TsigKey       string `yaml:"tsigKey" validate:"required_with=TsigSecret TsigSecretVaultPath"`
+	TsigAlgorithm string `yaml:"tsigAlgorithm" validate:"omitempty,oneof=hmac-sha1 hmac-sha224 hmac-sha256 hmac-sha384 hmac-sha512"`
+	TsigSecret    string `yaml:"tsigSecret"`
+	// TsigSecretVaultPath is the path of a secret in the KV2 mount that holds the TSIG secret in its 'tsig_secret' field.
+	TsigSecretVaultPath string `yaml:"tsigSecretVaultPath" validate:"omitempty,excluded_with=TsigSecret,startsnotwith=/,endsnotwith=/"`
+}
+
+func (conf *Rfc2136Config) UseTsig() bool {
+	return len(conf.TsigKey) > 0
+}
+
+// CloudflareConfig configures the Cloudflare provider. The secret must contain the field 'api_token' and may
+// contain a dedicated 'zone_token'.
+type CloudflareConfig struct {
+	SecretVaultPath string `yaml:"secretVaultPath" validate:"required,startsnotwith=/,endsnotwith=/"`
+}
+
+// HetznerConfig configures the Hetzner provider. The secret must contain the field 'api_key'.
+type HetznerConfig struct {
+	SecretVaultPath string `yaml:"secretVaultPath" validate:"required,startsnotwith=/,endsnotwith=/"`
+}
+
+// PowerDnsConfig configures the PowerDNS provider. The secret must contain the field 'api_key'.
+type PowerDnsConfig struct {
+	Url             string `yaml:"url" validate:"required,http_url"`
+	ServerName      string `yaml:"serverName"`
+	SecretVaultPath string `yaml:"secretVaultPath" validate:"required,startsnotwith=/,endsnotwith=/"`
+}
+
+// GetDnsProvider returns the config of the DNS provider with the given name. Besides the providers defined in
+// 'dnsProviders', the provider selected by 'acmeDnsProvider' using the top-level provider config blocks is returned.
+func (conf AcmeVaultConfig) GetDnsProvider(name string) (DnsProviderConfig, bool) {
+	for _, provider := range conf.DnsProviders {
+		if provider.Name == name {
+			return provider, true
+		}
+	}
+
+	if name != conf.AcmeDnsProvider || !IsDnsProviderType(name) {
+		return DnsProviderConfig{}, false
+	}
+
+	route53 := conf.Route53
+	return DnsProviderConfig{
+		Name:       name,
+		Type:       name,
+		Route53:    &route53,
+		Rfc2136:    conf.Rfc2136,
+		Cloudflare: conf.Cloudflare,
+		Hetzner:    conf.Hetzner,
+		PowerDns:   conf.PowerDns,
+	}, true
+}
+
+// GetDnsProviderNames returns the name of the DNS provider for each name (domain and SANs) of the given domain.
+func (conf AcmeVaultConfig) GetDnsProviderNames(domain DomainsConfig) map[string]string {
+	ret := map[string]string{}
+	for _, name := range append([]string{domain.Domain}, domain.Sans...) {
+		name = strings.TrimPrefix(name, "*.")
+		if provider, found := domain.SanDnsProviders[name]; found {
+			ret[name] = provider
+		} else if len(domain.DnsProvider) > 0 {
+			ret[name] = domain.DnsProvider
+		} else {
+			ret[name] = conf.AcmeDnsProvider
+		}
+	}
+	return ret
+}
+
+func (conf AcmeVaultConfig) validateDnsProviders() error {
+	if _, found := conf.GetDnsProvider(conf.AcmeDnsProvider); !found {
+		return fmt.Errorf("acmeDnsProvider %q is neither a known provider nor defined in dnsProviders", conf.AcmeDnsProvider)
+	}
+
+	var errs error
+	assigned := map[string]string{}
+	for _, domain := range conf.Domains {
+		for san := range domain.SanDnsProviders {
+			if san != domain.Domain && !slices.Contains(domain.Sans, san) {
+				errs = multierr.Append(errs, fmt.Errorf("domain %s: dns provider assigned to unknown san %q", domain.Domain, san))
+			}
+		}
+
+		for name, provider := range conf.GetDnsProviderNames(domain) {
+			if _, found := conf.GetDnsProvider(provider); !found {
+				errs = multierr.Append(errs, fmt.Errorf("domain %s: unknown dns provider %q", domain.Domain, provider))
+			}
+
+			if other, found := assigned[name]; found && other != provider {
+				errs = multierr.Append(errs, fmt.Errorf("%s is assigned to both dns providers %q and %q", name, other, provider))
+			}
+			assigned[name] = provider
+		}
+	}
+
+	return errs
+}
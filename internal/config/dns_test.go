@@ -0,0 +1,246 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// the builtin providers are registered by the acme package, which can't be imported here
+	for _, name := range []string{DnsProviderRoute53, DnsProviderRfc2136, DnsProviderCloudflare, DnsProviderHetzner, DnsProviderPowerDns} {
+		RegisterDnsProviderType(name)
+	}
+	os.Exit(m.Run())
+}
+
+func TestAcmeVaultConfig_validateDnsProviders(t *testing.T) {
+	providers := []DnsProviderConfig{
+		{
+			Name:       "cloudflare-lab",
+			Type:       DnsProviderCloudflare,
+			Cloudflare: &CloudflareConfig{SecretVaultPath: "dns/cloudflare"},
+		},
+		{
+			Name: "route53-prod",
+			Type: DnsProviderRoute53,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		conf    AcmeVaultConfig
+		wantErr bool
+	}{
+		{
+			name: "legacy provider",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: DnsProviderRoute53,
+				Domains:         []DomainsConfig{{Domain: "example.com"}},
+			},
+		},
+		{
+			name: "named default provider",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: "route53-prod",
+				DnsProviders:    providers,
+				Domains:         []DomainsConfig{{Domain: "example.com"}},
+			},
+		},
+		{
+			name: "per domain and san providers",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: "route53-prod",
+				DnsProviders:    providers,
+				Domains: []DomainsConfig{
+					{
+						Domain:          "example.com",
+						Sans:            []string{"lab.example.net"},
+						SanDnsProviders: map[string]string{"lab.example.net": "cloudflare-lab"},
+					},
+					{
+						Domain:      "example.org",
+						DnsProvider: "cloudflare-lab",
+					},
+				},
+			},
+		},
+		{
+			name: "unknown legacy provider",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: "unknown",
+				Domains:         []DomainsConfig{{Domain: "example.com"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown default provider",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: "route53-dev",
+				DnsProviders:    providers,
+				Domains:         []DomainsConfig{{Domain: "example.com"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown domain provider",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: DnsProviderRoute53,
+				DnsProviders:    providers,
+				Domains:         []DomainsConfig{{Domain: "example.com", DnsProvider: "route53-dev"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "provider for unknown san",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: DnsProviderRoute53,
+				DnsProviders:    providers,
+				Domains: []DomainsConfig{
+					{
+						Domain:          "example.com",
+						SanDnsProviders: map[string]string{"lab.example.net": "cloudflare-lab"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "name assigned to different providers",
+			conf: AcmeVaultConfig{
+				AcmeDnsProvider: "route53-prod",
+				DnsProviders:    providers,
+				Domains: []DomainsConfig{
+					{
+						Domain: "example.com",
+					},
+					{
+						Domain:      "www.example.com",
+						Sans:        []string{"example.com"},
+						DnsProvider: "cloudflare-lab",
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.conf.validateDnsProviders(); (err != nil) != tt.wantErr {
+				t.Errorf("validateDnsProviders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRfc2136Config_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    Rfc2136Config
+		wantErr bool
+	}{
+		{
+			name: "tsig secret from vault",
+			conf: Rfc2136Config{
+				Nameserver:          "ns1.example.com:53",
+				TsigKey:             "acmevault",
+				TsigAlgorithm:       "hmac-sha256",
+				TsigSecretVaultPath: "dns/rfc2136",
+			},
+		},
+		{
+			name: "tsig secret and vault path",
+			conf: Rfc2136Config{
+				Nameserver:          "ns1.example.com:53",
+				TsigKey:             "acmevault",
+				TsigSecret:          "IwBTJx9wrDp4Y1RyC3H0gA==",
+				TsigSecretVaultPath: "dns/rfc2136",
+			},
+			wantErr: true,
+		},
+		{
+			name: "tsig secret but no key",
+			conf: Rfc2136Config{
+				Nameserver: "ns1.example.com:53",
+				TsigSecret: "IwBTJx9wrDp4Y1RyC3H0gA==",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "without nameserver",
+			conf:    Rfc2136Config{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.conf); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCloudflareConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    CloudflareConfig
+		wantErr bool
+	}{
+		{
+			name: "secret from vault",
+			conf: CloudflareConfig{SecretVaultPath: "acmevault/dns/cloudflare"},
+		},
+		{
+			name:    "without secret path",
+			conf:    CloudflareConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "absolute secret path",
+			conf:    CloudflareConfig{SecretVaultPath: "/acmevault/dns/cloudflare"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.conf); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDnsProviderConfig_registeredType(t *testing.T) {
+	conf := DnsProviderConfig{Name: "custom-lab", Type: "custom"}
+	if err := validate.Struct(conf); err == nil {
+		t.Fatal("expected error for unknown dns provider type")
+	}
+
+	RegisterDnsProviderType("custom")
+	t.Cleanup(func() {
+		UnregisterDnsProviderType("custom")
+	})
+
+	if err := validate.Struct(conf); err != nil {
+		t.Errorf("registered dns provider type got error = %v", err)
+	}
+}
+
+func TestAcmeVaultConfig_GetDnsProviderNames(t *testing.T) {
+	conf := AcmeVaultConfig{AcmeDnsProvider: DnsProviderRoute53}
+	domain := DomainsConfig{
+		Domain:          "example.com",
+		Sans:            []string{"*.example.com", "lab.example.net"},
+		DnsProvider:     "bind",
+		SanDnsProviders: map[string]string{"lab.example.net": "cloudflare-lab"},
+	}
+
+	want := map[string]string{
+		"example.com":     "bind",
+		"lab.example.net": "cloudflare-lab",
+	}
+	if got := conf.GetDnsProviderNames(domain); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDnsProviderNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"time"
+)
+
+// defaultRenewalLifetimeFraction renews certs after two thirds of their lifetime, e.g. 30 days before expiry for
+// certs that are valid for 90 days.
+const defaultRenewalLifetimeFraction = 2.0 / 3
+
+// RenewalConfig defines when certs are renewed if the CA does not suggest a renewal window.
+type RenewalConfig struct {
+	// Before renews the cert once its remaining lifetime drops below the given duration.
+	Before time.Duration `yaml:"before,omitempty" env:"BEFORE" validate:"omitempty,min=1m"`
+	// LifetimeFraction renews the cert once the given fraction of its total lifetime has elapsed.
+	LifetimeFraction float64 `yaml:"lifetimeFraction,omitempty" env:"LIFETIME_FRACTION" validate:"omitempty,gt=0,lt=1,excluded_with=Before"`
+	// Jitter moves the renewal of each cert by up to the given duration to the past, to spread renewals.
+	Jitter time.Duration `yaml:"jitter,omitempty" env:"JITTER" validate:"min=0"`
+}
+
+// RenewalTime returns the point in time a cert with the given validity should be renewed. The jitter is derived from
+// the given seed, so the renewal time of a cert does not change between checks.
+func (r RenewalConfig) RenewalTime(notBefore, notAfter time.Time, seed uint64) time.Time {
+	var renewAt time.Time
+	if r.Before > 0 {
+		renewAt = notAfter.Add(-r.Before)
+	} else {
+		fraction := r.LifetimeFraction
+		if fraction <= 0 {
+			fraction = defaultRenewalLifetimeFraction
+		}
+		lifetime := notAfter.Sub(notBefore)
+		renewAt = notBefore.Add(time.Duration(float64(lifetime) * fraction))
+	}
+
+	if r.Jitter > 0 {
+		renewAt = renewAt.Add(-time.Duration(seed % uint64(r.Jitter)))
+	}
+
+	return renewAt
+}
+
+// GetRenewalConfig returns the renewal config of the domain, falling back to the defaults if none is set.
+func (a DomainsConfig) GetRenewalConfig() RenewalConfig {
+	if a.Renewal == nil {
+		return RenewalConfig{}
+	}
+
+	return *a.Renewal
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenewalConfig_RenewalTime(t *testing.T) {
+	day := 24 * time.Hour
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		config   RenewalConfig
+		lifetime time.Duration
+		seed     uint64
+		want     time.Time
+	}{
+		{
+			name:     "default",
+			config:   RenewalConfig{},
+			lifetime: 90 * day,
+			want:     notBefore.Add(60 * day),
+		},
+		{
+			name:     "default short-lived",
+			config:   RenewalConfig{},
+			lifetime: 6 * day,
+			want:     notBefore.Add(4 * day),
+		},
+		{
+			name:     "fixed duration",
+			config:   RenewalConfig{Before: 10 * day},
+			lifetime: 90 * day,
+			want:     notBefore.Add(80 * day),
+		},
+		{
+			name:     "fraction",
+			config:   RenewalConfig{LifetimeFraction: 0.5},
+			lifetime: 7 * day,
+			want:     notBefore.Add(84 * time.Hour),
+		},
+		{
+			name:     "jitter",
+			config:   RenewalConfig{Before: 10 * day, Jitter: day},
+			lifetime: 90 * day,
+			seed:     uint64(25 * time.Hour),
+			want:     notBefore.Add(80*day - time.Hour),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.RenewalTime(notBefore, notBefore.Add(tt.lifetime), tt.seed)
+			if !got.Equal(tt.want) {
+				t.Errorf("RenewalTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenewalConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  RenewalConfig
+		wantErr bool
+	}{
+		{
+			name:   "lifetime fraction",
+			config: RenewalConfig{LifetimeFraction: 0.5, Jitter: time.Hour},
+		},
+		{
+			name:    "before and lifetime fraction",
+			config:  RenewalConfig{Before: 24 * time.Hour, LifetimeFraction: 0.5},
+			wantErr: true,
+		},
+		{
+			name:    "lifetime fraction too large",
+			config:  RenewalConfig{LifetimeFraction: 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "negative jitter",
+			config:  RenewalConfig{Jitter: -time.Hour},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
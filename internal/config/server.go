@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/caarlos0/env/v10"
 	"gopkg.in/yaml.v3"
@@ -17,20 +19,47 @@ var (
 )
 
 type AcmeVaultConfig struct {
-	Vault                VaultConfig     `yaml:"vault" envPrefix:"VAULT_" validate:"required"`
-	AcmeEmail            string          `yaml:"email" env:"ACME_EMAIL" validate:"required,email"`
-	AcmeUrl              string          `yaml:"acmeUrl" env:"ACME_URL" validate:"required,oneof=https://acme-v02.api.letsencrypt.org/directory https://acme-staging-v02.api.letsencrypt.org/directory"`
-	AcmeDnsProvider      string          `yaml:"acmeDnsProvider" env:"ACME_DNS_PROVIDER"`
-	AcmeCustomDnsServers []string        `yaml:"acmeCustomDnsServers,omitempty" env:"ACME_CUSTOM_DNS_SERVERS" validate:"dive,ip"`
-	IntervalSeconds      int             `yaml:"intervalSeconds" env:"INTERVAL_SECONDS" validate:"min=3600,max=86400"`
-	Domains              []DomainsConfig `yaml:"domains" validate:"required,dive"`
-	MetricsAddr          string          `yaml:"metricsAddr" env:"METRICS_ADDR" validate:"omitempty,tcp_addr"`
-	Verbose              bool            `yaml:"verbose" env:"VERBOSE"`
+	Storage              string              `yaml:"storage" env:"STORAGE" validate:"omitempty,oneof=vault filesystem kubernetes"`
+	Vault                VaultConfig         `yaml:"vault" envPrefix:"VAULT_" validate:"required"`
+	Filesystem           *FilesystemConfig   `yaml:"filesystem,omitempty" validate:"required_if=Storage filesystem"`
+	Kubernetes           *KubernetesConfig   `yaml:"kubernetes,omitempty" validate:"required_if=Storage kubernetes"`
+	AcmeEmail            string              `yaml:"email" env:"ACME_EMAIL" validate:"required,email"`
+	AcmeUrl              string              `yaml:"acmeUrl" env:"ACME_URL" validate:"required,http_url,startswith=https://"`
+	AcmeCaBundle         string              `yaml:"acmeCaBundle,omitempty" env:"ACME_CA_BUNDLE" validate:"omitempty,file"`
+	AcmeEabKeyId         string              `yaml:"acmeEabKeyId,omitempty" env:"ACME_EAB_KEY_ID" validate:"required_with=AcmeEabHmac AcmeEabHmacVaultPath"`
+	AcmeEabHmac          string              `yaml:"acmeEabHmac,omitempty" env:"ACME_EAB_HMAC" validate:"omitempty,base64url|base64rawurl"`
+	AcmeEabHmacVaultPath string              `yaml:"acmeEabHmacVaultPath,omitempty" env:"ACME_EAB_HMAC_VAULT_PATH" validate:"omitempty,excluded_with=AcmeEabHmac,startsnotwith=/,endsnotwith=/"`
+	AcmeDnsProvider      string              `yaml:"acmeDnsProvider" env:"ACME_DNS_PROVIDER" validate:"required"`
+	AcmeCustomDnsServers []string            `yaml:"acmeCustomDnsServers,omitempty" env:"ACME_CUSTOM_DNS_SERVERS" validate:"dive,ip"`
+	IntervalSeconds      int                 `yaml:"intervalSeconds" env:"INTERVAL_SECONDS" validate:"min=3600,max=86400"`
+	KeyType              string              `yaml:"keyType" env:"KEY_TYPE" validate:"omitempty,oneof=ec256 ec384 rsa2048 rsa3072 rsa4096"`
+	Renewal              RenewalConfig       `yaml:"renewal" envPrefix:"RENEWAL_"`
+	Domains              []DomainsConfig     `yaml:"domains" validate:"required,dive"`
+	DnsProviders         []DnsProviderConfig `yaml:"dnsProviders,omitempty" validate:"unique=Name,dive"`
+	Route53              Route53Config       `yaml:"route53" envPrefix:"ROUTE53_"`
+	Rfc2136              *Rfc2136Config      `yaml:"rfc2136,omitempty" validate:"required_if=AcmeDnsProvider rfc2136"`
+	Cloudflare           *CloudflareConfig   `yaml:"cloudflare,omitempty" validate:"required_if=AcmeDnsProvider cloudflare"`
+	Hetzner              *HetznerConfig      `yaml:"hetzner,omitempty" validate:"required_if=AcmeDnsProvider hetzner"`
+	PowerDns             *PowerDnsConfig     `yaml:"pdns,omitempty" validate:"required_if=AcmeDnsProvider pdns"`
+	MetricsAddr          string              `yaml:"metricsAddr" env:"METRICS_ADDR" validate:"omitempty,tcp_addr"`
+	Verbose              bool                `yaml:"verbose" env:"VERBOSE"`
 }
 
 type DomainsConfig struct {
 	Domain string   `yaml:"domain" validate:"required,fqdn"`
 	Sans   []string `yaml:"sans,omitempty" validate:"dive,fqdn"`
+	// DnsProvider references the DNS provider that solves the challenges for this domain, defaults to acmeDnsProvider.
+	DnsProvider string `yaml:"dnsProvider,omitempty"`
+	// SanDnsProviders overrides the DNS provider for single SANs whose zones are hosted elsewhere.
+	SanDnsProviders map[string]string `yaml:"sanDnsProviders,omitempty"`
+	// KeyType is the type of the certificate's private key, defaults to keyType.
+	KeyType string `yaml:"keyType,omitempty" validate:"omitempty,oneof=ec256 ec384 rsa2048 rsa3072 rsa4096"`
+	// KeyPolicy defines whether the private key is rotated or reused when renewing the certificate.
+	KeyPolicy string `yaml:"keyPolicy,omitempty" validate:"omitempty,oneof=rotate reuse rotate-every"`
+	// KeyRotateEvery is the number of renewals after which the private key is rotated, used by the rotate-every policy.
+	KeyRotateEvery int `yaml:"keyRotateEvery,omitempty" validate:"required_if=KeyPolicy rotate-every,min=0"`
+	// Renewal overrides the global renewal config for this domain.
+	Renewal *RenewalConfig `yaml:"renewal,omitempty"`
 }
 
 func (a DomainsConfig) String() string {
@@ -41,13 +70,56 @@ func (a DomainsConfig) String() string {
 	return a.Domain
 }
 
+// UseExternalAccountBinding returns whether the account should be registered using an external account binding.
+func (conf AcmeVaultConfig) UseExternalAccountBinding() bool {
+	return len(conf.AcmeEabKeyId) > 0
+}
+
+// GetAcmeDirectoryHost returns the lowercase host of the ACME directory URL, which identifies the CA.
+func (conf AcmeVaultConfig) GetAcmeDirectoryHost() string {
+	return AcmeDirectoryHost(conf.AcmeUrl)
+}
+
+// getDefaultPathPrefix keeps data of different CAs apart by default. Let's Encrypt directories keep using the staging
+// host that has been the default before arbitrary directories were supported, so upgraded installations find their
+// existing certificates and accounts.
+func (conf AcmeVaultConfig) getDefaultPathPrefix() string {
+	if conf.AcmeUrl == letsEncryptUrl || conf.AcmeUrl == letsEncryptStagingUrl {
+		return AcmeDirectoryHost(letsEncryptStagingUrl)
+	}
+	return conf.GetAcmeDirectoryHost()
+}
+
+// AcmeDirectoryHost returns the lowercase host of an ACME directory URL in a form that can be used in paths.
+func AcmeDirectoryHost(directory string) string {
+	parsed, err := url.Parse(directory)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ReplaceAll(strings.ToLower(parsed.Host), ":", "_")
+}
+
 func (conf AcmeVaultConfig) Validate() error {
-	return validate.Struct(conf)
+	var err error
+	if conf.UseVault() {
+		err = validate.Struct(conf)
+	} else {
+		err = validate.StructExcept(conf, "Vault")
+	}
+	if err != nil {
+		return err
+	}
+
+	return conf.validateDnsProviders()
 }
 
 func getDefaultConfig() AcmeVaultConfig {
 	return AcmeVaultConfig{
 		AcmeUrl:         letsEncryptUrl,
+		Storage:         StorageVault,
+		AcmeDnsProvider: DnsProviderRoute53,
+		KeyType:         defaultKeyType,
 		IntervalSeconds: defaultIntervalSeconds,
 		MetricsAddr:     defaultMetricsAddr,
 		Vault:           defaultVaultConfig(),
@@ -79,5 +151,11 @@ func GetConfig(path string) (AcmeVaultConfig, error) {
 		return AcmeVaultConfig{}, err
 	}
 
+	if len(conf.Vault.PathPrefix) == 0 {
+		conf.Vault.PathPrefix = conf.getDefaultPathPrefix()
+	}
+
+	conf.setDomainDefaults()
+
 	return conf, nil
 }
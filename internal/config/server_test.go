@@ -1,8 +1,11 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestAcmeVaultServerConfigFromFile(t *testing.T) {
@@ -28,8 +31,10 @@ func TestAcmeVaultServerConfigFromFile(t *testing.T) {
 				},
 				AcmeEmail:       "my@email.tld",
 				AcmeUrl:         letsEncryptUrl,
-				AcmeDnsProvider: "",
+				AcmeDnsProvider: DnsProviderRoute53,
 				IntervalSeconds: 43200,
+				KeyType:         KeyTypeRsa4096,
+				Storage:         StorageVault,
 				Domains: []DomainsConfig{
 					{
 						Domain: "domain1.tld",
@@ -59,8 +64,10 @@ func TestAcmeVaultServerConfigFromFile(t *testing.T) {
 				},
 				AcmeEmail:       "my@email.tld",
 				AcmeUrl:         letsEncryptStagingUrl,
-				AcmeDnsProvider: "",
+				AcmeDnsProvider: DnsProviderRoute53,
 				IntervalSeconds: 43200,
+				KeyType:         KeyTypeRsa4096,
+				Storage:         StorageVault,
 				Domains: []DomainsConfig{
 					{
 						Domain: "domain1.tld",
@@ -99,6 +106,15 @@ func TestAcmeVaultServerConfig_Validate(t *testing.T) {
 		IntervalSeconds      int
 		Domains              []DomainsConfig
 		MetricsAddr          string
+		Rfc2136              *Rfc2136Config
+		Cloudflare           *CloudflareConfig
+		AcmeEabKeyId         string
+		AcmeEabHmac          string
+		AcmeEabHmacVaultPath string
+		KeyType              string
+		Storage              string
+		Filesystem           *FilesystemConfig
+		Kubernetes           *KubernetesConfig
 	}
 	tests := []struct {
 		name    string
@@ -122,7 +138,7 @@ func TestAcmeVaultServerConfig_Validate(t *testing.T) {
 				},
 				AcmeEmail:            "ac@me.com",
 				AcmeUrl:              letsEncryptUrl,
-				AcmeDnsProvider:      "",
+				AcmeDnsProvider:      DnsProviderRoute53,
 				AcmeCustomDnsServers: []string{"8.8.8.8", "2001:4860:4860::8888"},
 				IntervalSeconds:      3600,
 				Domains: []DomainsConfig{
@@ -135,6 +151,39 @@ func TestAcmeVaultServerConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "filesystem storage without vault",
+			fields: fields{
+				AcmeEmail:       "ac@me.com",
+				AcmeUrl:         letsEncryptUrl,
+				AcmeDnsProvider: DnsProviderRoute53,
+				IntervalSeconds: 3600,
+				Storage:         StorageFilesystem,
+				Filesystem:      &FilesystemConfig{Path: "/var/lib/acmevault"},
+				Domains: []DomainsConfig{
+					{
+						Domain: "valid.domain",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "vault storage without vault",
+			fields: fields{
+				AcmeEmail:       "ac@me.com",
+				AcmeUrl:         letsEncryptUrl,
+				AcmeDnsProvider: DnsProviderRoute53,
+				IntervalSeconds: 3600,
+				Storage:         StorageVault,
+				Domains: []DomainsConfig{
+					{
+						Domain: "valid.domain",
+					},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid custom dns servers",
 			fields: fields{
@@ -331,6 +380,15 @@ func TestAcmeVaultServerConfig_Validate(t *testing.T) {
 				IntervalSeconds:      tt.fields.IntervalSeconds,
 				Domains:              tt.fields.Domains,
 				MetricsAddr:          tt.fields.MetricsAddr,
+				Rfc2136:              tt.fields.Rfc2136,
+				Cloudflare:           tt.fields.Cloudflare,
+				AcmeEabKeyId:         tt.fields.AcmeEabKeyId,
+				AcmeEabHmac:          tt.fields.AcmeEabHmac,
+				AcmeEabHmacVaultPath: tt.fields.AcmeEabHmacVaultPath,
+				KeyType:              tt.fields.KeyType,
+				Storage:              tt.fields.Storage,
+				Filesystem:           tt.fields.Filesystem,
+				Kubernetes:           tt.fields.Kubernetes,
 			}
 			if err := conf.Validate(); (err != nil) != tt.wantErr {
 				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
@@ -338,3 +396,207 @@ func TestAcmeVaultServerConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestAcmeVaultConfig_ValidateFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    AcmeVaultConfig
+		fields  []string
+		wantErr bool
+	}{
+		{
+			name:    "rfc2136 without config block",
+			conf:    AcmeVaultConfig{AcmeDnsProvider: DnsProviderRfc2136},
+			fields:  []string{"AcmeDnsProvider", "Rfc2136"},
+			wantErr: true,
+		},
+		{
+			name:   "custom acme directory",
+			conf:   AcmeVaultConfig{AcmeUrl: "https://acme.zerossl.com/v2/DV90"},
+			fields: []string{"AcmeUrl"},
+		},
+		{
+			name:   "private acme directory with port",
+			conf:   AcmeVaultConfig{AcmeUrl: "https://ca.internal:9000/acme/acme/directory"},
+			fields: []string{"AcmeUrl"},
+		},
+		{
+			name:    "acme directory without tls",
+			conf:    AcmeVaultConfig{AcmeUrl: "http://ca.internal/acme/acme/directory"},
+			fields:  []string{"AcmeUrl"},
+			wantErr: true,
+		},
+		{
+			name:   "eab with hmac",
+			conf:   AcmeVaultConfig{AcmeEabKeyId: "kid", AcmeEabHmac: "c2VjcmV0LWhtYWMta2V5"},
+			fields: []string{"AcmeEabKeyId", "AcmeEabHmac", "AcmeEabHmacVaultPath"},
+		},
+		{
+			name:   "eab with hmac from vault",
+			conf:   AcmeVaultConfig{AcmeEabKeyId: "kid", AcmeEabHmacVaultPath: "acmevault/eab"},
+			fields: []string{"AcmeEabKeyId", "AcmeEabHmac", "AcmeEabHmacVaultPath"},
+		},
+		{
+			name:    "eab hmac without key id",
+			conf:    AcmeVaultConfig{AcmeEabHmac: "c2VjcmV0LWhtYWMta2V5"},
+			fields:  []string{"AcmeEabKeyId", "AcmeEabHmac", "AcmeEabHmacVaultPath"},
+			wantErr: true,
+		},
+		{
+			name:    "eab hmac from config and vault",
+			conf:    AcmeVaultConfig{AcmeEabKeyId: "kid", AcmeEabHmac: "c2VjcmV0LWhtYWMta2V5", AcmeEabHmacVaultPath: "acmevault/eab"},
+			fields:  []string{"AcmeEabKeyId", "AcmeEabHmac", "AcmeEabHmacVaultPath"},
+			wantErr: true,
+		},
+		{
+			name:   "ecdsa key type",
+			conf:   AcmeVaultConfig{KeyType: KeyTypeEc256},
+			fields: []string{"KeyType"},
+		},
+		{
+			name:    "invalid key type",
+			conf:    AcmeVaultConfig{KeyType: "ec521"},
+			fields:  []string{"KeyType"},
+			wantErr: true,
+		},
+		{
+			name:    "filesystem storage without config",
+			conf:    AcmeVaultConfig{Storage: StorageFilesystem},
+			fields:  []string{"Storage", "Filesystem"},
+			wantErr: true,
+		},
+		{
+			name:    "kubernetes storage without config",
+			conf:    AcmeVaultConfig{Storage: StorageKubernetes},
+			fields:  []string{"Storage", "Kubernetes"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.StructPartial(tt.conf, tt.fields...); (err != nil) != tt.wantErr {
+				t.Errorf("StructPartial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAcmeDirectoryHost(t *testing.T) {
+	tests := []struct {
+		directory string
+		want      string
+	}{
+		{
+			directory: letsEncryptUrl,
+			want:      "acme-v02.api.letsencrypt.org",
+		},
+		{
+			directory: "https://CA.internal:9000/acme/acme/directory",
+			want:      "ca.internal_9000",
+		},
+		{
+			directory: "://invalid",
+			want:      "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.directory, func(t *testing.T) {
+			if got := AcmeDirectoryHost(tt.directory); got != tt.want {
+				t.Errorf("AcmeDirectoryHost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetConfig_derivePathPrefix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := []byte("acmeUrl: https://acme.zerossl.com/v2/DV90\n")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := GetConfig(path)
+	if err != nil {
+		t.Fatalf("GetConfig() error = %v", err)
+	}
+
+	if conf.Vault.PathPrefix != "acme.zerossl.com" {
+		t.Errorf("expected path prefix to be derived from acme directory, got %q", conf.Vault.PathPrefix)
+	}
+}
+
+func TestGetConfig_legacyPathPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "upgraded production config",
+			content: "acmeUrl: " + letsEncryptUrl + "\n",
+			want:    "acme-staging-v02.api.letsencrypt.org",
+		},
+		{
+			name:    "upgraded staging config",
+			content: "acmeUrl: " + letsEncryptStagingUrl + "\n",
+			want:    "acme-staging-v02.api.letsencrypt.org",
+		},
+		{
+			name:    "explicit path prefix",
+			content: "acmeUrl: " + letsEncryptUrl + "\nvault:\n  pathPrefix: production\n",
+			want:    "production",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			conf, err := GetConfig(path)
+			if err != nil {
+				t.Fatalf("GetConfig() error = %v", err)
+			}
+
+			if conf.Vault.PathPrefix != tt.want {
+				t.Errorf("PathPrefix = %q, want %q", conf.Vault.PathPrefix, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetConfig_domainDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := []byte(`keyType: ec256
+renewal:
+  before: 240h
+domains:
+  - domain: example.com
+  - domain: legacy.example.com
+    keyType: rsa2048
+    renewal:
+      lifetimeFraction: 0.5
+`)
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := GetConfig(path)
+	if err != nil {
+		t.Fatalf("GetConfig() error = %v", err)
+	}
+
+	if conf.Domains[0].KeyType != KeyTypeEc256 {
+		t.Errorf("expected default key type %q, got %q", KeyTypeEc256, conf.Domains[0].KeyType)
+	}
+	if conf.Domains[1].KeyType != KeyTypeRsa2048 {
+		t.Errorf("expected key type %q, got %q", KeyTypeRsa2048, conf.Domains[1].KeyType)
+	}
+	if want := (RenewalConfig{Before: 240 * time.Hour}); conf.Domains[0].GetRenewalConfig() != want {
+		t.Errorf("expected default renewal config %v, got %v", want, conf.Domains[0].GetRenewalConfig())
+	}
+	if want := (RenewalConfig{LifetimeFraction: 0.5}); conf.Domains[1].GetRenewalConfig() != want {
+		t.Errorf("expected renewal config %v, got %v", want, conf.Domains[1].GetRenewalConfig())
+	}
+}
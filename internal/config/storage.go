@@ -0,0 +1,33 @@
+package config
+
+const (
+	StorageVault      = "vault"
+	StorageFilesystem = "filesystem"
+	StorageKubernetes = "kubernetes"
+)
+
+// FilesystemConfig configures the storage of certs and accounts on the local filesystem instead of Vault.
+type FilesystemConfig struct {
+	// Path is the directory that holds all data.
+	Path string `yaml:"path" validate:"required"`
+}
+
+// KubernetesConfig configures the storage of certs and accounts as Kubernetes secrets instead of Vault.
+type KubernetesConfig struct {
+	// Kubeconfig is the path to a kubeconfig file, the in-cluster config is used if it's empty.
+	Kubeconfig string `yaml:"kubeconfig" validate:"omitempty,filepath"`
+
+	// Namespace is the namespace the secrets are written to.
+	Namespace string `yaml:"namespace" validate:"required"`
+
+	// SecretNameFormat is the format of the name of a certificate's secret, the domain is passed as single argument.
+	SecretNameFormat string `yaml:"secretNameFormat" validate:"omitempty,contains=%s"`
+
+	// AccountSecretName is the name of the secret holding the ACME account.
+	AccountSecretName string `yaml:"accountSecretName"`
+}
+
+// UseVault returns whether data is stored in Vault.
+func (conf AcmeVaultConfig) UseVault() bool {
+	return conf.Storage == "" || conf.Storage == StorageVault
+}
@@ -0,0 +1,58 @@
+package config
+
+import "testing"
+
+func TestFilesystemConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  FilesystemConfig
+		wantErr bool
+	}{
+		{
+			name:   "path",
+			config: FilesystemConfig{Path: "/var/lib/acmevault"},
+		},
+		{
+			name:    "without path",
+			config:  FilesystemConfig{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKubernetesConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  KubernetesConfig
+		wantErr bool
+	}{
+		{
+			name:   "namespace and secret name format",
+			config: KubernetesConfig{Namespace: "acmevault", SecretNameFormat: "%s-tls"},
+		},
+		{
+			name:    "without namespace",
+			config:  KubernetesConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid secret name format",
+			config:  KubernetesConfig{Namespace: "acmevault", SecretNameFormat: "tls"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
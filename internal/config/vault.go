@@ -1,7 +1,6 @@
 package config
 
 import (
-	"net/url"
 	"os"
 	"strings"
 
@@ -10,36 +9,73 @@ import (
 
 var validate = validator.New()
 
+const (
+	KvVersion1    = "1"
+	KvVersion2    = "2"
+	KvVersionAuto = "auto"
+)
+
 type VaultConfig struct {
-	Addr       string `yaml:"addr" env:"ADDR" validate:"required,http_url"`
-	AuthMethod string `yaml:"authMethod" env:"AUTH_METHOD" validate:"required,oneof=token approle kubernetes implicit"`
+	Addr       string `yaml:"addr" env:"ADDR" validate:"required_unless=AuthMethod 'agent',omitempty,http_url"`
+	AuthMethod string `yaml:"authMethod" env:"AUTH_METHOD" validate:"required,oneof=token approle kubernetes cert jwt aws agent implicit"`
 	Token      string `yaml:"token" env:"TOKEN" validate:"required_if=AuthMethod 'token'"`
 
+	// AgentAddr is the address of a local Vault Agent or Vault Proxy handling auto-auth, either a unix socket
+	// (unix:///path/to/socket) or a http listener.
+	AgentAddr string `yaml:"agentAddr" env:"AGENT_ADDR" validate:"required_if=AuthMethod 'agent',omitempty,uri"`
+
+	// ImplicitTokenFiles are read in order by the implicit auth method before falling back to ~/.vault-token.
+	ImplicitTokenFiles []string `yaml:"implicitTokenFiles" env:"IMPLICIT_TOKEN_FILES" validate:"excluded_unless=AuthMethod 'implicit',dive,filepath"`
+
+	CaCert             string `yaml:"caCert" env:"CA_CERT" validate:"omitempty,file"`
+	CaPath             string `yaml:"caPath" env:"CA_PATH" validate:"omitempty,dir"`
+	ClientCert         string `yaml:"clientCert" env:"CLIENT_CERT" validate:"required_with=ClientKey,omitempty,file"`
+	ClientKey          string `yaml:"clientKey" env:"CLIENT_KEY" validate:"required_with=ClientCert,omitempty,file"`
+	TlsServerName      string `yaml:"tlsServerName" env:"TLS_SERVER_NAME"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify" env:"INSECURE_SKIP_VERIFY"`
+
+	// Namespace is the Vault Enterprise namespace of the secrets mounts.
+	Namespace string `yaml:"namespace" env:"NAMESPACE" validate:"omitempty,startsnotwith=/"`
+	// AuthNamespace is the namespace of the auth mount, defaults to Namespace.
+	AuthNamespace string `yaml:"authNamespace" env:"AUTH_NAMESPACE" validate:"omitempty,startsnotwith=/"`
+
 	RoleId       string `yaml:"roleId" env:"APPROLE_ROLE_ID" validate:"required_if=AuthMethod 'approle'"`
-	SecretId     string `yaml:"secretId" env:"APPROLE_SECRET_ID" validate:"excluded_unless=SecretIdFile '',required_if=SecretIdFile '' AuthMethod 'approle'"`
-	SecretIdFile string `yaml:"secretIdFile" env:"APPROLE_SECRET_ID_FILE" validate:"excluded_unless=SecretId '',required_if=SecretId '' AuthMethod 'approle'"`
+	SecretId     string `yaml:"secretId" env:"APPROLE_SECRET_ID" validate:"excluded_unless=SecretIdFile '' SecretIdWrappingToken '' SecretIdWrappingTokenFile '',required_if=SecretIdFile '' SecretIdWrappingToken '' SecretIdWrappingTokenFile '' AuthMethod 'approle'"`
+	SecretIdFile string `yaml:"secretIdFile" env:"APPROLE_SECRET_ID_FILE" validate:"excluded_unless=SecretId '' SecretIdWrappingToken '' SecretIdWrappingTokenFile ''"`
+	// SecretIdWrappingToken is a response-wrapped token containing the secret id, it's unwrapped once at startup.
+	SecretIdWrappingToken     string `yaml:"secretIdWrappingToken" env:"APPROLE_SECRET_ID_WRAPPING_TOKEN" validate:"excluded_unless=SecretId '' SecretIdFile '' SecretIdWrappingTokenFile ''"`
+	SecretIdWrappingTokenFile string `yaml:"secretIdWrappingTokenFile" env:"APPROLE_SECRET_ID_WRAPPING_TOKEN_FILE" validate:"excluded_unless=SecretId '' SecretIdFile '' SecretIdWrappingToken '',omitempty,file"`
 
 	K8sRoleId    string `yaml:"k8sRoleId" env:"K8S_ROLE_ID" validate:"required_if=AuthMethod 'kubernetes'"`
 	K8sMountPath string `yaml:"k8sMountPath" env:"K8S_MOUNT" `
 
+	CertMountPath  string `yaml:"certMountPath" env:"CERT_MOUNT"`
+	CertRole       string `yaml:"certRole" env:"CERT_ROLE"`
+	CertClientCert string `yaml:"certClientCert" env:"CERT_CLIENT_CERT" validate:"required_if=AuthMethod 'cert',omitempty,file"`
+	CertClientKey  string `yaml:"certClientKey" env:"CERT_CLIENT_KEY" validate:"required_if=AuthMethod 'cert',omitempty,file"`
+
+	JwtMountPath string `yaml:"jwtMountPath" env:"JWT_MOUNT"`
+	JwtRole      string `yaml:"jwtRole" env:"JWT_ROLE" validate:"required_if=AuthMethod 'jwt'"`
+	JwtFile      string `yaml:"jwtFile" env:"JWT_FILE" validate:"required_if=AuthMethod 'jwt',omitempty,filepath"`
+
+	AwsAuthMountPath string `yaml:"awsAuthMountPath" env:"AWS_AUTH_MOUNT"`
+	AwsAuthRole      string `yaml:"awsAuthRole" env:"AWS_AUTH_ROLE" validate:"required_if=AuthMethod 'aws'"`
+	AwsAuthServerId  string `yaml:"awsAuthServerId" env:"AWS_AUTH_SERVER_ID"`
+	AwsAuthRegion    string `yaml:"awsAuthRegion" env:"AWS_AUTH_REGION"`
+
 	PathPrefix       string `yaml:"pathPrefix" env:"PATH_PREFIX" validate:"required,startsnotwith=/,startsnotwith=/secret,endsnotwith=/,ne=acmevault"`
 	DomainPathFormat string `yaml:"domainPathFormat" env:"DOMAIN_PATH_FORMAT" validate:"omitempty,containsrune=%"`
 
 	Kv2MountPath string `yaml:"kv2MountPath" env:"KV2_MOUNT" validate:"required,endsnotwith=/,startsnotwith=/"`
+	// KvVersion is the version of the KV secrets engine mounted at Kv2MountPath, defaults to 2. Use "auto" to detect it.
+	KvVersion string `yaml:"kvVersion" env:"KV_VERSION" validate:"omitempty,oneof=1 2 auto"`
 
 	AwsMountPath string `yaml:"awsMountPath" env:"AWS_MOUNT" validate:"required,endsnotwith=/,startsnotwith=/"`
 	AwsRole      string `yaml:"awsRole" env:"AWS_ROLE" validate:"required"`
 }
 
 func defaultVaultConfig() VaultConfig {
-	var pathPrefix string
-	parsed, err := url.Parse(letsEncryptStagingUrl)
-	if err == nil {
-		pathPrefix = strings.ToLower(parsed.Host)
-	}
-
 	return VaultConfig{
-		PathPrefix:   pathPrefix,
 		Token:        os.Getenv("VAULT_TOKEN"),
 		Addr:         os.Getenv("VAULT_ADDR"),
 		AwsRole:      "acmevault",
@@ -56,6 +92,51 @@ func (conf *VaultConfig) LoadSecretIdFromFile() bool {
 	return len(conf.SecretIdFile) > 0
 }
 
+// UseWrappedSecretId returns whether the AppRole secret id is handed out as response-wrapped token.
+func (conf *VaultConfig) UseWrappedSecretId() bool {
+	return len(conf.SecretIdWrappingToken) > 0 || len(conf.SecretIdWrappingTokenFile) > 0
+}
+
+// GetSecretIdWrappingToken returns the wrapping token of the secret id, reading it from file if configured.
+func (conf *VaultConfig) GetSecretIdWrappingToken() (string, error) {
+	if len(conf.SecretIdWrappingToken) > 0 {
+		return conf.SecretIdWrappingToken, nil
+	}
+
+	data, err := os.ReadFile(conf.SecretIdWrappingTokenFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// GetAuthNamespace returns the namespace to login to.
+func (conf *VaultConfig) GetAuthNamespace() string {
+	if len(conf.AuthNamespace) > 0 {
+		return conf.AuthNamespace
+	}
+	return conf.Namespace
+}
+
+// UseClientCert returns whether a client certificate is presented to Vault.
+func (conf *VaultConfig) UseClientCert() bool {
+	return len(conf.ClientCert) > 0 && len(conf.ClientKey) > 0
+}
+
+// UseAgent returns whether requests are sent to a local Vault Agent or Vault Proxy that handles authentication.
+func (conf *VaultConfig) UseAgent() bool {
+	return conf.AuthMethod == "agent"
+}
+
+// GetAddr returns the address acmevault connects to.
+func (conf *VaultConfig) GetAddr() string {
+	if conf.UseAgent() {
+		return conf.AgentAddr
+	}
+	return conf.Addr
+}
+
 func (conf *VaultConfig) UseAutoRenewAuth() bool {
-	return conf.AuthMethod != "token" && conf.AuthMethod != "implicit"
+	return conf.AuthMethod != "token" && conf.AuthMethod != "implicit" && !conf.UseAgent()
 }
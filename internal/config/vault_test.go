@@ -1,6 +1,10 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestVaultConfig_Validate(t *testing.T) {
 	type fields struct {
@@ -151,3 +155,227 @@ func TestVaultConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestVaultConfig_ValidateFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    VaultConfig
+		fields  []string
+		wantErr bool
+	}{
+		{
+			name:   "kv version 1",
+			conf:   VaultConfig{KvVersion: KvVersion1},
+			fields: []string{"KvVersion"},
+		},
+		{
+			name:   "kv version auto",
+			conf:   VaultConfig{KvVersion: KvVersionAuto},
+			fields: []string{"KvVersion"},
+		},
+		{
+			name:    "invalid kv version",
+			conf:    VaultConfig{KvVersion: "3"},
+			fields:  []string{"KvVersion"},
+			wantErr: true,
+		},
+		{
+			name:   "tls",
+			conf:   VaultConfig{CaCert: "vault_test.go", ClientCert: "vault_test.go", ClientKey: "vault_test.go"},
+			fields: []string{"CaCert", "ClientCert", "ClientKey"},
+		},
+		{
+			name:    "client key without cert",
+			conf:    VaultConfig{ClientKey: "vault_test.go"},
+			fields:  []string{"ClientCert", "ClientKey"},
+			wantErr: true,
+		},
+		{
+			name:    "ca cert missing",
+			conf:    VaultConfig{CaCert: "missing.pem"},
+			fields:  []string{"CaCert"},
+			wantErr: true,
+		},
+		{
+			name:   "cert auth",
+			conf:   VaultConfig{AuthMethod: "cert", CertClientCert: "vault_test.go", CertClientKey: "vault_test.go"},
+			fields: []string{"AuthMethod", "CertClientCert", "CertClientKey"},
+		},
+		{
+			name:    "cert auth without cert",
+			conf:    VaultConfig{AuthMethod: "cert"},
+			fields:  []string{"AuthMethod", "CertClientCert", "CertClientKey"},
+			wantErr: true,
+		},
+		{
+			name:   "jwt auth",
+			conf:   VaultConfig{AuthMethod: "jwt", JwtRole: "acmevault", JwtFile: "/var/run/secrets/tokens/vault"},
+			fields: []string{"AuthMethod", "JwtRole", "JwtFile"},
+		},
+		{
+			name:    "jwt auth without role",
+			conf:    VaultConfig{AuthMethod: "jwt", JwtFile: "/var/run/secrets/tokens/vault"},
+			fields:  []string{"AuthMethod", "JwtRole", "JwtFile"},
+			wantErr: true,
+		},
+		{
+			name:   "aws auth",
+			conf:   VaultConfig{AuthMethod: "aws", AwsAuthRole: "acmevault"},
+			fields: []string{"AuthMethod", "AwsAuthRole"},
+		},
+		{
+			name:    "aws auth without role",
+			conf:    VaultConfig{AuthMethod: "aws"},
+			fields:  []string{"AuthMethod", "AwsAuthRole"},
+			wantErr: true,
+		},
+		{
+			name:   "approle wrapped secret id",
+			conf:   VaultConfig{AuthMethod: "approle", SecretIdWrappingToken: "hvs.wrapped"},
+			fields: []string{"SecretId", "SecretIdFile", "SecretIdWrappingToken", "SecretIdWrappingTokenFile"},
+		},
+		{
+			name:   "approle wrapped secret id file",
+			conf:   VaultConfig{AuthMethod: "approle", SecretIdWrappingTokenFile: "vault_test.go"},
+			fields: []string{"SecretId", "SecretIdFile", "SecretIdWrappingToken", "SecretIdWrappingTokenFile"},
+		},
+		{
+			name:    "approle wrapped and plain secret id",
+			conf:    VaultConfig{AuthMethod: "approle", SecretId: "secret-id", SecretIdWrappingToken: "hvs.wrapped"},
+			fields:  []string{"SecretId", "SecretIdFile", "SecretIdWrappingToken", "SecretIdWrappingTokenFile"},
+			wantErr: true,
+		},
+		{
+			name:    "approle without secret id",
+			conf:    VaultConfig{AuthMethod: "approle"},
+			fields:  []string{"SecretId", "SecretIdFile", "SecretIdWrappingToken", "SecretIdWrappingTokenFile"},
+			wantErr: true,
+		},
+		{
+			name:   "agent socket",
+			conf:   VaultConfig{AuthMethod: "agent", AgentAddr: "unix:///run/vault-agent.sock"},
+			fields: []string{"Addr", "AgentAddr"},
+		},
+		{
+			name:   "agent listener",
+			conf:   VaultConfig{AuthMethod: "agent", AgentAddr: "http://127.0.0.1:8100"},
+			fields: []string{"Addr", "AgentAddr"},
+		},
+		{
+			name:    "agent without address",
+			conf:    VaultConfig{AuthMethod: "agent"},
+			fields:  []string{"Addr", "AgentAddr"},
+			wantErr: true,
+		},
+		{
+			name:   "implicit token files",
+			conf:   VaultConfig{AuthMethod: "implicit", ImplicitTokenFiles: []string{"/run/vault/token"}},
+			fields: []string{"ImplicitTokenFiles"},
+		},
+		{
+			name:    "token files without implicit auth",
+			conf:    VaultConfig{AuthMethod: "token", ImplicitTokenFiles: []string{"/run/vault/token"}},
+			fields:  []string{"ImplicitTokenFiles"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.StructPartial(tt.conf, tt.fields...); (err != nil) != tt.wantErr {
+				t.Errorf("StructPartial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVaultConfig_GetAuthNamespace(t *testing.T) {
+	tests := []struct {
+		name string
+		conf VaultConfig
+		want string
+	}{
+		{
+			name: "no namespaces",
+			conf: VaultConfig{},
+			want: "",
+		},
+		{
+			name: "auth namespace defaults to namespace",
+			conf: VaultConfig{Namespace: "org/secrets"},
+			want: "org/secrets",
+		},
+		{
+			name: "separate auth namespace",
+			conf: VaultConfig{Namespace: "org/secrets", AuthNamespace: "org/auth"},
+			want: "org/auth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.conf.GetAuthNamespace(); got != tt.want {
+				t.Errorf("GetAuthNamespace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultConfig_GetSecretIdWrappingToken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "wrapped")
+	if err := os.WriteFile(file, []byte("hvs.from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		conf    VaultConfig
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "from string",
+			conf: VaultConfig{SecretIdWrappingToken: "hvs.wrapped"},
+			want: "hvs.wrapped",
+		},
+		{
+			name: "from file",
+			conf: VaultConfig{SecretIdWrappingTokenFile: file},
+			want: "hvs.from-file",
+		},
+		{
+			name:    "missing file",
+			conf:    VaultConfig{SecretIdWrappingTokenFile: filepath.Join(t.TempDir(), "missing")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.conf.GetSecretIdWrappingToken()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetSecretIdWrappingToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetSecretIdWrappingToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultConfig_agent(t *testing.T) {
+	conf := VaultConfig{
+		Addr:       "https://vault:8200",
+		AgentAddr:  "unix:///run/vault-agent.sock",
+		AuthMethod: "agent",
+	}
+
+	if got := conf.GetAddr(); got != conf.AgentAddr {
+		t.Errorf("GetAddr() = %v, want %v", got, conf.AgentAddr)
+	}
+	if conf.UseAutoRenewAuth() {
+		t.Errorf("UseAutoRenewAuth() = true, token must be managed by the agent")
+	}
+
+	conf.AuthMethod = "approle"
+	if got := conf.GetAddr(); got != conf.Addr {
+		t.Errorf("GetAddr() = %v, want %v", got, conf.Addr)
+	}
+}
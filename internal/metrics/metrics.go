@@ -59,6 +59,20 @@ var (
 		Help:      "Expiration date of the token",
 	})
 
+	VaultTokenSwaps = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystemVaultRenewal,
+		Name:      "token_swaps_total",
+		Help:      "Total number of tokens swapped after the token file changed",
+	})
+
+	VaultTokenSwapErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystemVaultRenewal,
+		Name:      "token_swap_errors_total",
+		Help:      "Total errors while reading a changed token file",
+	})
+
 	ServerLatestIterationTimestamp = promauto.NewGauge(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Subsystem: "server",
@@ -94,6 +108,27 @@ var (
 		Help:      "Total errors while trying to renew certificates",
 	})
 
+	CertificatesReissued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "server",
+		Name:      "certificates_reissued_total",
+		Help:      "Total number of certificates that were reissued instead of renewed",
+	}, []string{"domain", "reason"})
+
+	CertificatesRevocations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "server",
+		Name:      "certificates_revocations_total",
+		Help:      "Total number of revoked certificates",
+	})
+
+	CertificatesRevokeErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "server",
+		Name:      "certificates_revocation_errors_total",
+		Help:      "Total errors while trying to revoke certificates",
+	})
+
 	CertWrites = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
 		Subsystem: "server",
@@ -115,6 +150,13 @@ var (
 		Help:      "Timestamp of certificate expiry",
 	}, []string{"domain"})
 
+	CertRenewalWindowStart = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "server",
+		Name:      "certificate_renewal_window_start_time",
+		Help:      "Timestamp of the start of the renewal window suggested by the CA",
+	}, []string{"domain"})
+
 	AwsDynCredentialsRequested = promauto.NewCounter(prometheus.CounterOpts{
 		Namespace: namespace,
 		Subsystem: "server",
@@ -129,6 +171,20 @@ var (
 		Help:      "Total amount of errors while trying to acquire dynamic AWS credentials",
 	})
 
+	DnsProviderSecretRotations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "server",
+		Name:      "dns_provider_secret_rotations_total",
+		Help:      "Total number of rebuilt DNS providers due to a changed secret",
+	}, []string{"provider"})
+
+	DnsProviderSecretErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "server",
+		Name:      "dns_provider_secret_errors_total",
+		Help:      "Total number of errors while reading a DNS provider secret or building the provider with it",
+	}, []string{"provider"})
+
 	CertErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
 		Subsystem: "server",
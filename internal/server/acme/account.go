@@ -9,9 +9,17 @@ type AccountStorage interface {
 	// WriteAccount writes an ACME account to the storage.
 	WriteAccount(account certstorage.AcmeAccount) error
 
-	// ReadAccount reads the ACME account data for a given email address from the storage.
-	ReadAccount(email string) (*certstorage.AcmeAccount, error)
+	// ReadAccount reads the ACME account data for a given email address at the CA identified by its directory URL
+	// from the storage.
+	ReadAccount(directory, email string) (*certstorage.AcmeAccount, error)
 
 	// Logout cleans up and logs out of the storage subsystem.
 	Logout() error
 }
+
+// SecretBackend reads secrets from the storage, such as API tokens of DNS providers or the HMAC key for the external
+// account binding, so they don't need to be configured on the host.
+type SecretBackend interface {
+	// ReadSecret reads the secret data stored at the given path.
+	ReadSecret(path string) (map[string]interface{}, error)
+}
@@ -0,0 +1,38 @@
+package acme
+
+import (
+	"errors"
+
+	"github.com/go-acme/lego/v4/challenge"
+	legoCloudflare "github.com/go-acme/lego/v4/providers/dns/cloudflare"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+const (
+	CloudflareVaultKeyApiToken  = "api_token"
+	CloudflareVaultKeyZoneToken = "zone_token"
+)
+
+func init() {
+	RegisterDnsProvider(config.DnsProviderCloudflare, buildCloudflare)
+}
+
+func buildCloudflare(conf config.DnsProviderConfig, deps DnsProviderDeps) (challenge.Provider, error) {
+	if conf.Cloudflare == nil {
+		return nil, errors.New("no cloudflare config provided")
+	}
+
+	return NewSecretProvider(conf.Name, conf.Cloudflare.SecretVaultPath, deps.Secrets, newCloudflareProvider)
+}
+
+func newCloudflareProvider(secret ProviderSecret) (challenge.Provider, error) {
+	token, err := secret.Get(CloudflareVaultKeyApiToken)
+	if err != nil {
+		return nil, err
+	}
+
+	legoConf := legoCloudflare.NewDefaultConfig()
+	legoConf.AuthToken = token
+	legoConf.ZoneToken = secret.GetOptional(CloudflareVaultKeyZoneToken)
+	return legoCloudflare.NewDNSProviderConfig(legoConf)
+}
@@ -0,0 +1,184 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+// DnsProviderDeps contains the runtime dependencies that DNS providers may need to get built.
+type DnsProviderDeps struct {
+	AwsCredentials     aws.CredentialsProvider
+	AwsRoleCredentials AwsRoleCredentialsBackend
+	Secrets            SecretBackend
+}
+
+// DnsProviderBuilder builds a DNS-01 challenge provider from its config block.
+type DnsProviderBuilder func(conf config.DnsProviderConfig, deps DnsProviderDeps) (challenge.Provider, error)
+
+var dnsProviders = map[string]DnsProviderBuilder{}
+
+// RegisterDnsProvider makes a DNS provider available under the given name, which is also accepted by the config
+// validation afterwards. It panics if a provider with the same name has already been registered.
+func RegisterDnsProvider(name string, builder DnsProviderBuilder) {
+	if builder == nil {
+		panic("acme: nil builder for dns provider " + name)
+	}
+
+	if _, found := dnsProviders[name]; found {
+		panic("acme: dns provider registered twice: " + name)
+	}
+
+	dnsProviders[name] = builder
+	config.RegisterDnsProviderType(name)
+}
+
+// DnsProviders returns the sorted names of all registered DNS providers.
+func DnsProviders() []string {
+	names := make([]string, 0, len(dnsProviders))
+	for name := range dnsProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuildDnsProvider builds a single DNS provider instance.
+func BuildDnsProvider(conf config.DnsProviderConfig, deps DnsProviderDeps) (challenge.Provider, error) {
+	builder, found := dnsProviders[conf.Type]
+	if !found {
+		return nil, fmt.Errorf("unknown dns provider %q, available providers: %v", conf.Type, DnsProviders())
+	}
+
+	return builder(conf, deps)
+}
+
+// BuildDnsProviders builds all DNS providers that are referenced by the configured domains. If domains reference
+// different providers, a provider is returned that dispatches the challenges to the provider configured for the
+// respective name.
+func BuildDnsProviders(conf config.AcmeVaultConfig, deps DnsProviderDeps) (challenge.Provider, error) {
+	providers := map[string]challenge.Provider{}
+	assigned := map[string]string{}
+	for _, domain := range conf.Domains {
+		for name, providerName := range conf.GetDnsProviderNames(domain) {
+			assigned[name] = providerName
+			if _, built := providers[providerName]; built {
+				continue
+			}
+
+			providerConf, found := conf.GetDnsProvider(providerName)
+			if !found {
+				return nil, fmt.Errorf("domain %s references unknown dns provider %q", domain.Domain, providerName)
+			}
+
+			log.Info().Msgf("Building dns provider %s", providerConf)
+			provider, err := BuildDnsProvider(providerConf, deps)
+			if err != nil {
+				return nil, fmt.Errorf("could not build dns provider %s: %w", providerConf, err)
+			}
+			providers[providerName] = provider
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, errors.New("no dns provider referenced")
+	}
+
+	if len(providers) == 1 {
+		for _, provider := range providers {
+			return provider, nil
+		}
+	}
+
+	return newDnsProviderDispatcher(providers, assigned), nil
+}
+
+// dnsProviderDispatcher dispatches challenges to the DNS provider that is configured for the challenged name.
+type dnsProviderDispatcher struct {
+	providers map[string]challenge.Provider
+	// assigned maps the names to the name of the provider that solves their challenges.
+	assigned map[string]string
+}
+
+func newDnsProviderDispatcher(providers map[string]challenge.Provider, assigned map[string]string) challenge.Provider {
+	dispatcher := &dnsProviderDispatcher{
+		providers: providers,
+		assigned:  assigned,
+	}
+
+	// lego decides whether to solve challenges sequentially by checking the provider's interfaces, so we can
+	// only offer it if any of the dispatched providers is sequential.
+	var interval time.Duration
+	var sequential bool
+	for _, provider := range providers {
+		if seq, ok := provider.(interface{ Sequential() time.Duration }); ok {
+			sequential = true
+			interval = max(interval, seq.Sequential())
+		}
+	}
+
+	if sequential {
+		return &sequentialDnsProviderDispatcher{dnsProviderDispatcher: dispatcher, interval: interval}
+	}
+	return dispatcher
+}
+
+func (d *dnsProviderDispatcher) getProvider(domain string) (challenge.Provider, error) {
+	name := strings.TrimPrefix(dns01.UnFqdn(domain), "*.")
+	providerName, found := d.assigned[name]
+	if !found {
+		return nil, fmt.Errorf("no dns provider configured for %s", name)
+	}
+
+	return d.providers[providerName], nil
+}
+
+// Present creates a TXT record to fulfill the DNS-01 challenge using the provider configured for the domain.
+func (d *dnsProviderDispatcher) Present(domain, token, keyAuth string) error {
+	provider, err := d.getProvider(domain)
+	if err != nil {
+		return err
+	}
+	return provider.Present(domain, token, keyAuth)
+}
+
+// CleanUp removes the TXT record created for the DNS-01 challenge using the provider configured for the domain.
+func (d *dnsProviderDispatcher) CleanUp(domain, token, keyAuth string) error {
+	provider, err := d.getProvider(domain)
+	if err != nil {
+		return err
+	}
+	return provider.CleanUp(domain, token, keyAuth)
+}
+
+// Timeout returns the most lenient timeout and interval of all providers as lego does not tell which domain is
+// being checked.
+func (d *dnsProviderDispatcher) Timeout() (timeout, interval time.Duration) {
+	timeout, interval = dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+	for _, provider := range d.providers {
+		if p, ok := provider.(challenge.ProviderTimeout); ok {
+			providerTimeout, providerInterval := p.Timeout()
+			timeout = max(timeout, providerTimeout)
+			interval = max(interval, providerInterval)
+		}
+	}
+	return timeout, interval
+}
+
+type sequentialDnsProviderDispatcher struct {
+	*dnsProviderDispatcher
+	interval time.Duration
+}
+
+// Sequential returns the interval between solving the challenges, which are resolved sequentially.
+func (d *sequentialDnsProviderDispatcher) Sequential() time.Duration {
+	return d.interval
+}
@@ -0,0 +1,151 @@
+package acme
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+func TestDnsProviders(t *testing.T) {
+	// every provider that is accepted by the config validation must be buildable
+	for _, name := range []string{config.DnsProviderRoute53, config.DnsProviderRfc2136, config.DnsProviderCloudflare, config.DnsProviderHetzner, config.DnsProviderPowerDns} {
+		if _, found := dnsProviders[name]; !found {
+			t.Errorf("dns provider %q is not registered", name)
+		}
+	}
+}
+
+func TestRegisterDnsProvider(t *testing.T) {
+	RegisterDnsProvider("custom", func(_ config.DnsProviderConfig, _ DnsProviderDeps) (challenge.Provider, error) {
+		return nil, nil
+	})
+	t.Cleanup(func() {
+		delete(dnsProviders, "custom")
+		config.UnregisterDnsProviderType("custom")
+	})
+
+	// every registered provider must be accepted by the config validation
+	for _, name := range DnsProviders() {
+		if !config.IsDnsProviderType(name) {
+			t.Errorf("dns provider %q is not accepted by the config validation", name)
+		}
+	}
+}
+
+func TestRegisterDnsProvider_unregistered(t *testing.T) {
+	// providers registered by other tests must not leak into the config validation
+	if config.IsDnsProviderType("custom") {
+		t.Error("dns provider \"custom\" is accepted by the config validation without being registered")
+	}
+}
+
+func TestBuildDnsProvider_unknown(t *testing.T) {
+	conf := config.DnsProviderConfig{Name: "unknown", Type: "unknown"}
+	if _, err := BuildDnsProvider(conf, DnsProviderDeps{}); err == nil {
+		t.Error("expected error for unknown dns provider")
+	}
+}
+
+func TestBuildDnsProviders(t *testing.T) {
+	conf := config.AcmeVaultConfig{
+		AcmeDnsProvider: config.DnsProviderRoute53,
+		DnsProviders: []config.DnsProviderConfig{
+			{
+				Name:    "bind",
+				Type:    config.DnsProviderRfc2136,
+				Rfc2136: &config.Rfc2136Config{Nameserver: "127.0.0.1:53"},
+			},
+			{
+				Name:       "cloudflare-lab",
+				Type:       config.DnsProviderCloudflare,
+				Cloudflare: &config.CloudflareConfig{SecretVaultPath: "dns/cloudflare"},
+			},
+		},
+		Domains: []config.DomainsConfig{
+			{
+				Domain: "example.com",
+			},
+			{
+				Domain:          "internal.example.org",
+				Sans:            []string{"lab.example.net"},
+				DnsProvider:     "bind",
+				SanDnsProviders: map[string]string{"lab.example.net": "cloudflare-lab"},
+			},
+		},
+	}
+
+	deps := DnsProviderDeps{Secrets: &staticSecretBackend{}}
+	provider, err := BuildDnsProviders(conf, deps)
+	if err != nil {
+		t.Fatalf("BuildDnsProviders() error = %v", err)
+	}
+
+	// the rfc2136 provider solves challenges sequentially, so the dispatcher has to as well
+	dispatcher, ok := provider.(*sequentialDnsProviderDispatcher)
+	if !ok {
+		t.Fatalf("expected sequential dispatcher, got %T", provider)
+	}
+
+	if len(dispatcher.providers) != 3 {
+		t.Errorf("expected 3 providers to be built, got %d", len(dispatcher.providers))
+	}
+
+	want := map[string]string{
+		"example.com":          config.DnsProviderRoute53,
+		"internal.example.org": "bind",
+		"lab.example.net":      "cloudflare-lab",
+	}
+	for name, providerName := range want {
+		got, err := dispatcher.getProvider(name + ".")
+		if err != nil {
+			t.Errorf("getProvider(%s) error = %v", name, err)
+			continue
+		}
+		if got != dispatcher.providers[providerName] {
+			t.Errorf("getProvider(%s) did not return provider %s", name, providerName)
+		}
+	}
+
+	if _, err := dispatcher.getProvider("unknown.example.com"); err == nil {
+		t.Error("expected error for name without provider")
+	}
+}
+
+type timeoutProvider struct {
+	recordingProvider
+	timeout, interval time.Duration
+}
+
+func (p *timeoutProvider) Timeout() (time.Duration, time.Duration) {
+	return p.timeout, p.interval
+}
+
+func TestDnsProviderDispatcher(t *testing.T) {
+	a := &timeoutProvider{timeout: 5 * time.Minute, interval: time.Second}
+	b := &recordingProvider{}
+	provider := newDnsProviderDispatcher(map[string]challenge.Provider{"a": a, "b": b}, map[string]string{
+		"example.com":     "a",
+		"sub.example.org": "b",
+	})
+
+	if _, ok := provider.(*dnsProviderDispatcher); !ok {
+		t.Fatalf("expected non-sequential dispatcher, got %T", provider)
+	}
+
+	if err := provider.Present("*.example.com", "", ""); err != nil {
+		t.Fatalf("Present() error = %v", err)
+	}
+	if err := provider.CleanUp("sub.example.org", "", ""); err != nil {
+		t.Fatalf("CleanUp() error = %v", err)
+	}
+	if a.presents != 1 || b.cleanups != 1 || a.cleanups != 0 || b.presents != 0 {
+		t.Errorf("challenges dispatched to wrong providers")
+	}
+
+	timeout, interval := provider.(challenge.ProviderTimeout).Timeout()
+	if timeout != 5*time.Minute || interval != 2*time.Second {
+		t.Errorf("Timeout() = %v, %v", timeout, interval)
+	}
+}
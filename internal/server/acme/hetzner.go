@@ -0,0 +1,34 @@
+package acme
+
+import (
+	"errors"
+
+	"github.com/go-acme/lego/v4/challenge"
+	legoHetzner "github.com/go-acme/lego/v4/providers/dns/hetzner"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+const HetznerVaultKeyApiKey = "api_key"
+
+func init() {
+	RegisterDnsProvider(config.DnsProviderHetzner, buildHetzner)
+}
+
+func buildHetzner(conf config.DnsProviderConfig, deps DnsProviderDeps) (challenge.Provider, error) {
+	if conf.Hetzner == nil {
+		return nil, errors.New("no hetzner config provided")
+	}
+
+	return NewSecretProvider(conf.Name, conf.Hetzner.SecretVaultPath, deps.Secrets, newHetznerProvider)
+}
+
+func newHetznerProvider(secret ProviderSecret) (challenge.Provider, error) {
+	apiKey, err := secret.Get(HetznerVaultKeyApiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	legoConf := legoHetzner.NewDefaultConfig()
+	legoConf.APIKey = apiKey
+	return legoHetzner.NewDNSProviderConfig(legoConf)
+}
@@ -1,10 +1,15 @@
 package acme
 
 import (
+	"crypto"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"regexp"
 
+	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/certificate"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/challenge/dns01"
@@ -15,23 +20,36 @@ import (
 	"github.com/soerenschneider/acmevault/pkg/certstorage"
 )
 
-const DnsProviderRoute53 = "route53"
-
 // removes double line breaks
 var lineBreaksRegex = regexp.MustCompile(`(\r\n?|\n){2,}`)
 
+// EabVaultKeyHmac is the name of the field that holds the EAB HMAC key in the secret read from Vault.
+const EabVaultKeyHmac = "hmac"
+
 type GoLego struct {
 	client *lego.Client
+	// eab is set if the account should be registered using an external account binding
+	eab *registration.RegisterEABOptions
 }
 
-func buildLegoClient(account *certstorage.AcmeAccount, acmeUrl string) (*GoLego, error) {
+func buildLegoClient(account *certstorage.AcmeAccount, conf config.AcmeVaultConfig) (*GoLego, error) {
+	keyType, err := getCertKeyType(conf.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
 	legoConfig := lego.NewConfig(account)
-	legoConfig.Certificate.KeyType = certPrivateKeyType
-	if len(acmeUrl) > 0 {
-		legoConfig.CADirURL = acmeUrl
+	legoConfig.Certificate.KeyType = keyType
+	if len(conf.AcmeUrl) > 0 {
+		legoConfig.CADirURL = conf.AcmeUrl
+	}
+
+	if len(conf.AcmeCaBundle) > 0 {
+		if err := addCaBundle(legoConfig.HTTPClient, conf.AcmeCaBundle); err != nil {
+			return nil, err
+		}
 	}
 
-	var err error
 	l := &GoLego{}
 	l.client, err = lego.NewClient(legoConfig)
 	if err != nil {
@@ -40,10 +58,39 @@ func buildLegoClient(account *certstorage.AcmeAccount, acmeUrl string) (*GoLego,
 	return l, nil
 }
 
-func getAccount(accountStorage AccountStorage, email string) (*certstorage.AcmeAccount, bool, error) {
-	account, err := accountStorage.ReadAccount(email)
+// addCaBundle adds the certificates of the given bundle to the trusted CAs of the http client, which is needed for
+// private ACME servers.
+func addCaBundle(client *http.Client, caBundle string) error {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return errors.New("can not set ca bundle on http client")
+	}
+
+	pem, err := os.ReadFile(caBundle)
+	if err != nil {
+		return fmt.Errorf("could not read ca bundle: %w", err)
+	}
+
+	pool := transport.TLSClientConfig.RootCAs
+	if pool == nil {
+		pool, err = x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in ca bundle %s", caBundle)
+	}
+
+	transport.TLSClientConfig.RootCAs = pool
+	return nil
+}
+
+func getAccount(accountStorage AccountStorage, directory, email string) (*certstorage.AcmeAccount, bool, error) {
+	account, err := accountStorage.ReadAccount(directory, email)
 	if err == nil {
-		log.Info().Str("email", email).Msg("retrieved account data")
+		log.Info().Str("email", email).Str("directory", directory).Msg("retrieved account data")
 		return account, false, nil
 	}
 
@@ -58,24 +105,59 @@ func getAccount(accountStorage AccountStorage, email string) (*certstorage.AcmeA
 	}
 
 	return &certstorage.AcmeAccount{
-		Email: email,
-		Key:   key,
+		Email:     email,
+		Key:       key,
+		Directory: directory,
 	}, true, nil
 }
 
-func NewGoLegoDealer(accountStorage AccountStorage, conf config.AcmeVaultConfig, dnsProvider challenge.Provider) (*GoLego, error) {
+// getEabHmac returns the HMAC key for the external account binding, either from the config or from the storage.
+func getEabHmac(conf config.AcmeVaultConfig, secrets SecretBackend) (string, error) {
+	if len(conf.AcmeEabHmacVaultPath) == 0 {
+		if len(conf.AcmeEabHmac) == 0 {
+			return "", errors.New("eab key id given but no hmac")
+		}
+		return conf.AcmeEabHmac, nil
+	}
+
+	if secrets == nil {
+		return "", errors.New("eab hmac should be read from vault but no secret backend provided")
+	}
+
+	data, err := secrets.ReadSecret(conf.AcmeEabHmacVaultPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read eab hmac: %w", err)
+	}
+
+	return ProviderSecret(data).Get(EabVaultKeyHmac)
+}
+
+func NewGoLegoDealer(accountStorage AccountStorage, conf config.AcmeVaultConfig, dnsProvider challenge.Provider, secrets SecretBackend) (*GoLego, error) {
 	log.Info().Str("email", conf.AcmeEmail).Msg("Trying to read account details from vault")
-	account, registerNewAccount, err := getAccount(accountStorage, conf.AcmeEmail)
+	account, registerNewAccount, err := getAccount(accountStorage, conf.AcmeUrl, conf.AcmeEmail)
 	if err != nil {
 		return nil, err
 	}
 
-	l, err := buildLegoClient(account, conf.AcmeUrl)
+	l, err := buildLegoClient(account, conf)
 	if err != nil {
 		return nil, err
 	}
 
 	if registerNewAccount {
+		if conf.UseExternalAccountBinding() {
+			hmac, err := getEabHmac(conf, secrets)
+			if err != nil {
+				return nil, err
+			}
+			l.eab = &registration.RegisterEABOptions{
+				TermsOfServiceAgreed: true,
+				Kid:                  conf.AcmeEabKeyId,
+				HmacEncoded:          hmac,
+			}
+			account.EabKeyId = conf.AcmeEabKeyId
+		}
+
 		registration, err := l.RegisterAccount()
 		if err != nil {
 			return nil, fmt.Errorf("can not register new account: %v", err)
@@ -99,15 +181,25 @@ func NewGoLegoDealer(accountStorage AccountStorage, conf config.AcmeVaultConfig,
 }
 
 func (l *GoLego) RegisterAccount() (*registration.Resource, error) {
+	if l.eab != nil {
+		log.Info().Str("kid", l.eab.Kid).Msg("Registering account using external account binding")
+		return l.client.Registration.RegisterWithExternalAccountBinding(*l.eab)
+	}
 	return l.client.Registration.Register(registration.RegisterOptions{TermsOfServiceAgreed: true})
 }
 
 func (l *GoLego) ObtainCert(domain config.DomainsConfig) (*certstorage.AcmeCertificate, error) {
 	domains := []string{domain.Domain}
 	domains = append(domains, domain.Sans...)
+	privateKey, err := generateCertPrivateKey(domain)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate private key: %w", err)
+	}
+
 	request := certificate.ObtainRequest{
-		Domains: domains,
-		Bundle:  true,
+		Domains:    domains,
+		Bundle:     true,
+		PrivateKey: privateKey,
 	}
 
 	legoCert, err := l.client.Certificate.Obtain(request)
@@ -118,40 +210,47 @@ func (l *GoLego) ObtainCert(domain config.DomainsConfig) (*certstorage.AcmeCerti
 	return &acmeCert, nil
 }
 
-func (l *GoLego) RenewCert(cert *certstorage.AcmeCertificate) (*certstorage.AcmeCertificate, error) {
+func (l *GoLego) RenewCert(domain config.DomainsConfig, cert *certstorage.AcmeCertificate) (*certstorage.AcmeCertificate, error) {
 	if cert == nil {
 		return nil, errors.New("empty certificate provided")
 	}
 
-	oldLego := toLego(cert)
-	oldLego.PrivateKey = nil
-	opts := &certificate.RenewOptions{
-		Bundle:         false,
-		PreferredChain: "",
-		MustStaple:     false,
-	}
-	newlegoCert, err := l.client.Certificate.RenewWithOptions(oldLego, opts)
+	x509Cert, err := parseLeafCertificate(cert)
 	if err != nil {
 		return nil, err
 	}
-	acmeCert := fromLego(newlegoCert)
-	return &acmeCert, nil
-}
 
-func toLego(other *certstorage.AcmeCertificate) certificate.Resource {
-	if nil == other {
-		return certificate.Resource{}
+	// the private key of the certificate is only reused if it has been provided, otherwise a new private key of the
+	// configured type is generated.
+	var privateKey crypto.PrivateKey
+	if len(cert.PrivateKey) > 0 {
+		privateKey, err = certcrypto.ParsePEMPrivateKey(cert.PrivateKey)
+	} else {
+		privateKey, err = generateCertPrivateKey(domain)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get private key: %w", err)
 	}
 
-	return certificate.Resource{
-		Domain:            other.Domain,
-		CertURL:           other.CertURL,
-		CertStableURL:     other.CertStableURL,
-		PrivateKey:        other.PrivateKey,
-		Certificate:       other.Certificate,
-		IssuerCertificate: other.IssuerCertificate,
-		CSR:               other.CSR,
+	request := certificate.ObtainRequest{
+		Domains:    append([]string{domain.Domain}, domain.Sans...),
+		Bundle:     false,
+		PrivateKey: privateKey,
+	}
+
+	// mark the order as replacement of the existing cert, this is only sent to CAs that support ARI
+	request.ReplacesCertID, err = certificate.MakeARICertID(x509Cert)
+	if err != nil {
+		log.Warn().Str("domain", cert.Domain).Err(err).Msg("Could not build ARI cert id")
 	}
+
+	log.Info().Str("domain", cert.Domain).Msg("Trying renewal of cert")
+	newlegoCert, err := l.client.Certificate.Obtain(request)
+	if err != nil {
+		return nil, err
+	}
+	acmeCert := fromLego(newlegoCert)
+	return &acmeCert, nil
 }
 
 func fromLego(other *certificate.Resource) certstorage.AcmeCertificate {
@@ -1,8 +1,17 @@
 package acme
 
 import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/go-acme/lego/v4/lego"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
 )
 
 func Test_fixLineBreaks(t *testing.T) {
@@ -28,3 +37,82 @@ more example data
 		t.Errorf("Expected %s, got %s", string(wanted), string(got))
 	}
 }
+
+func Test_addCaBundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caBundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caBundle, certPem, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := lego.NewConfig(&certstorage.AcmeAccount{}).HTTPClient
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("expected request to fail for untrusted CA")
+	}
+
+	if err := addCaBundle(client, caBundle); err != nil {
+		t.Fatalf("addCaBundle() error = %v", err)
+	}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected request to succeed with ca bundle: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if err := addCaBundle(client, filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("expected error for missing ca bundle")
+	}
+}
+
+func Test_getEabHmac(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.AcmeVaultConfig
+		secrets SecretBackend
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "hmac from config",
+			conf: config.AcmeVaultConfig{AcmeEabKeyId: "kid", AcmeEabHmac: "aG1hYw"},
+			want: "aG1hYw",
+		},
+		{
+			name:    "hmac from vault",
+			conf:    config.AcmeVaultConfig{AcmeEabKeyId: "kid", AcmeEabHmacVaultPath: "acmevault/eab"},
+			secrets: &staticSecretBackend{data: map[string]interface{}{EabVaultKeyHmac: "dmF1bHQ"}},
+			want:    "dmF1bHQ",
+		},
+		{
+			name:    "no hmac",
+			conf:    config.AcmeVaultConfig{AcmeEabKeyId: "kid"},
+			wantErr: true,
+		},
+		{
+			name:    "no secret backend",
+			conf:    config.AcmeVaultConfig{AcmeEabKeyId: "kid", AcmeEabHmacVaultPath: "acmevault/eab"},
+			wantErr: true,
+		},
+		{
+			name:    "missing field in vault secret",
+			conf:    config.AcmeVaultConfig{AcmeEabKeyId: "kid", AcmeEabHmacVaultPath: "acmevault/eab"},
+			secrets: &staticSecretBackend{data: map[string]interface{}{"key": "dmF1bHQ"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getEabHmac(tt.conf, tt.secrets)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getEabHmac() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getEabHmac() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
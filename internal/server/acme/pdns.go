@@ -0,0 +1,45 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/go-acme/lego/v4/challenge"
+	legoPdns "github.com/go-acme/lego/v4/providers/dns/pdns"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+const PowerDnsVaultKeyApiKey = "api_key"
+
+func init() {
+	RegisterDnsProvider(config.DnsProviderPowerDns, buildPowerDns)
+}
+
+func buildPowerDns(conf config.DnsProviderConfig, deps DnsProviderDeps) (challenge.Provider, error) {
+	if conf.PowerDns == nil {
+		return nil, errors.New("no pdns config provided")
+	}
+
+	host, err := url.Parse(conf.PowerDns.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pdns url: %w", err)
+	}
+
+	build := func(secret ProviderSecret) (challenge.Provider, error) {
+		apiKey, err := secret.Get(PowerDnsVaultKeyApiKey)
+		if err != nil {
+			return nil, err
+		}
+
+		legoConf := legoPdns.NewDefaultConfig()
+		legoConf.Host = host
+		legoConf.APIKey = apiKey
+		if len(conf.PowerDns.ServerName) > 0 {
+			legoConf.ServerName = conf.PowerDns.ServerName
+		}
+		return legoPdns.NewDNSProviderConfig(legoConf)
+	}
+
+	return NewSecretProvider(conf.Name, conf.PowerDns.SecretVaultPath, deps.Secrets, build)
+}
@@ -2,6 +2,7 @@ package acme
 
 import (
 	"crypto"
+	"fmt"
 
 	"github.com/go-acme/lego/v4/certcrypto"
 	"github.com/go-acme/lego/v4/registration"
@@ -11,15 +12,48 @@ import (
 
 const (
 	accountPrivateKeyType = certcrypto.RSA4096
-	certPrivateKeyType    = certcrypto.RSA4096
+	defaultCertKeyType    = certcrypto.RSA4096
 )
 
+var certKeyTypes = map[string]certcrypto.KeyType{
+	config.KeyTypeEc256:   certcrypto.EC256,
+	config.KeyTypeEc384:   certcrypto.EC384,
+	config.KeyTypeRsa2048: certcrypto.RSA2048,
+	config.KeyTypeRsa3072: certcrypto.RSA3072,
+	config.KeyTypeRsa4096: certcrypto.RSA4096,
+}
+
 type AcmeDealer interface {
 	RegisterAccount() (*registration.Resource, error)
 	ObtainCert(domain config.DomainsConfig) (*certstorage.AcmeCertificate, error)
-	RenewCert(cert *certstorage.AcmeCertificate) (*certstorage.AcmeCertificate, error)
+	RenewCert(domain config.DomainsConfig, cert *certstorage.AcmeCertificate) (*certstorage.AcmeCertificate, error)
+	GetRenewalInfo(cert *certstorage.AcmeCertificate) (*RenewalInfo, error)
+	RevokeCert(cert *certstorage.AcmeCertificate, reason uint) error
 }
 
 func GeneratePrivateKey() (crypto.PrivateKey, error) {
 	return certcrypto.GeneratePrivateKey(accountPrivateKeyType)
 }
+
+// getCertKeyType translates the key type from the config to the lego key type, an empty key type yields the default.
+func getCertKeyType(keyType string) (certcrypto.KeyType, error) {
+	if len(keyType) == 0 {
+		return defaultCertKeyType, nil
+	}
+
+	legoKeyType, ok := certKeyTypes[keyType]
+	if !ok {
+		return "", fmt.Errorf("unknown key type %q", keyType)
+	}
+	return legoKeyType, nil
+}
+
+// generateCertPrivateKey generates a new private key for a certificate of the given domain.
+func generateCertPrivateKey(domain config.DomainsConfig) (crypto.PrivateKey, error) {
+	keyType, err := getCertKeyType(domain.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	return certcrypto.GeneratePrivateKey(keyType)
+}
@@ -0,0 +1,183 @@
+package acme
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/metrics"
+)
+
+// ProviderSecret is the secret data a DNS provider needs to authenticate against its API.
+type ProviderSecret map[string]interface{}
+
+// Get returns the value of a mandatory field of the secret.
+func (s ProviderSecret) Get(key string) (string, error) {
+	val := s.GetOptional(key)
+	if len(val) == 0 {
+		return "", fmt.Errorf("no field '%s' found in secret", key)
+	}
+	return val, nil
+}
+
+// GetOptional returns the value of an optional field of the secret or an empty string if it's not set.
+func (s ProviderSecret) GetOptional(key string) string {
+	val, ok := s[key].(string)
+	if !ok {
+		return ""
+	}
+	return val
+}
+
+func (s ProviderSecret) fingerprint() [sha256.Size]byte {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(hash, "%s=%v\n", key, s[key])
+	}
+
+	var ret [sha256.Size]byte
+	copy(ret[:], hash.Sum(nil))
+	return ret
+}
+
+// ProviderSecretBuilder builds a DNS provider using the given secret.
+type ProviderSecretBuilder func(secret ProviderSecret) (challenge.Provider, error)
+
+// SecretProvider is a DNS provider that reads its secret from the storage before solving a challenge. When the
+// secret has changed since the last challenge, e.g. because it has been rotated, the underlying provider is rebuilt
+// with the new secret. This way, no long-lived secret needs to be configured on the host.
+type SecretProvider struct {
+	// name is the name of the configured provider instance, it's used to label logs and metrics.
+	name       string
+	secretPath string
+	secrets    SecretBackend
+	build      ProviderSecretBuilder
+
+	mutex       sync.Mutex
+	provider    challenge.Provider
+	fingerprint [sha256.Size]byte
+	// presented keeps track of which provider instance presented a challenge, as some providers need to remember
+	// the created records to clean them up again.
+	presented map[string]challenge.Provider
+}
+
+func NewSecretProvider(name, secretPath string, secrets SecretBackend, build ProviderSecretBuilder) (*SecretProvider, error) {
+	if len(secretPath) == 0 {
+		return nil, fmt.Errorf("%s: no secret path provided", name)
+	}
+
+	if secrets == nil {
+		return nil, fmt.Errorf("%s: no secret backend provided", name)
+	}
+
+	if build == nil {
+		return nil, fmt.Errorf("%s: no builder provided", name)
+	}
+
+	return &SecretProvider{
+		name:       name,
+		secretPath: secretPath,
+		secrets:    secrets,
+		build:      build,
+		presented:  map[string]challenge.Provider{},
+	}, nil
+}
+
+// refresh reads the secret and rebuilds the underlying provider if the secret has changed.
+func (p *SecretProvider) refresh() (challenge.Provider, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	data, err := p.secrets.ReadSecret(p.secretPath)
+	if err != nil {
+		metrics.DnsProviderSecretErrors.WithLabelValues(p.name).Inc()
+		if p.provider != nil {
+			log.Warn().Err(err).Str("provider", p.name).Msg("Could not read dns provider secret, using previously read secret")
+			return p.provider, nil
+		}
+		return nil, fmt.Errorf("%s: could not read secret: %w", p.name, err)
+	}
+
+	secret := ProviderSecret(data)
+	fingerprint := secret.fingerprint()
+	if p.provider != nil && fingerprint == p.fingerprint {
+		return p.provider, nil
+	}
+
+	provider, err := p.build(secret)
+	if err != nil {
+		metrics.DnsProviderSecretErrors.WithLabelValues(p.name).Inc()
+		return nil, fmt.Errorf("%s: could not build provider: %w", p.name, err)
+	}
+
+	if p.provider != nil {
+		log.Info().Str("provider", p.name).Msg("DNS provider secret has changed, rebuilt provider")
+		metrics.DnsProviderSecretRotations.WithLabelValues(p.name).Inc()
+	}
+
+	p.provider = provider
+	p.fingerprint = fingerprint
+	return p.provider, nil
+}
+
+func challengeKey(domain, keyAuth string) string {
+	return domain + "/" + keyAuth
+}
+
+// Present creates a TXT record to fulfill the DNS-01 challenge.
+func (p *SecretProvider) Present(domain, token, keyAuth string) error {
+	provider, err := p.refresh()
+	if err != nil {
+		return err
+	}
+
+	if err := provider.Present(domain, token, keyAuth); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	p.presented[challengeKey(domain, keyAuth)] = provider
+	p.mutex.Unlock()
+	return nil
+}
+
+// CleanUp removes the TXT record created for the DNS-01 challenge using the same provider instance that created it.
+func (p *SecretProvider) CleanUp(domain, token, keyAuth string) error {
+	key := challengeKey(domain, keyAuth)
+	p.mutex.Lock()
+	provider, found := p.presented[key]
+	delete(p.presented, key)
+	if !found {
+		provider = p.provider
+	}
+	p.mutex.Unlock()
+
+	if provider == nil {
+		return errors.New("no provider available to clean up challenge")
+	}
+
+	return provider.CleanUp(domain, token, keyAuth)
+}
+
+// Timeout returns the timeout and interval of the underlying provider to use when checking for DNS propagation.
+func (p *SecretProvider) Timeout() (timeout, interval time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if provider, ok := p.provider.(challenge.ProviderTimeout); ok {
+		return provider.Timeout()
+	}
+	return dns01.DefaultPropagationTimeout, dns01.DefaultPollingInterval
+}
@@ -0,0 +1,141 @@
+package acme
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-acme/lego/v4/challenge"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/internal/metrics"
+)
+
+type recordingProvider struct {
+	secret   string
+	presents int
+	cleanups int
+}
+
+func (p *recordingProvider) Present(_, _, _ string) error {
+	p.presents++
+	return nil
+}
+
+func (p *recordingProvider) CleanUp(_, _, _ string) error {
+	p.cleanups++
+	return nil
+}
+
+func TestSecretProvider_rotation(t *testing.T) {
+	backend := &staticSecretBackend{data: map[string]interface{}{"api_token": "first"}}
+	var built []*recordingProvider
+	build := func(secret ProviderSecret) (challenge.Provider, error) {
+		token, err := secret.Get("api_token")
+		if err != nil {
+			return nil, err
+		}
+		p := &recordingProvider{secret: token}
+		built = append(built, p)
+		return p, nil
+	}
+
+	provider, err := NewSecretProvider("test", "dns/test", backend, build)
+	if err != nil {
+		t.Fatalf("NewSecretProvider() error = %v", err)
+	}
+
+	if err := provider.Present("a.example.com", "", "a"); err != nil {
+		t.Fatalf("Present() error = %v", err)
+	}
+	if err := provider.Present("b.example.com", "", "b"); err != nil {
+		t.Fatalf("Present() error = %v", err)
+	}
+	if len(built) != 1 {
+		t.Fatalf("expected provider to be built once for unchanged secret, got %d", len(built))
+	}
+
+	// rotate the secret
+	backend.data = map[string]interface{}{"api_token": "second"}
+	if err := provider.Present("c.example.com", "", "c"); err != nil {
+		t.Fatalf("Present() error = %v", err)
+	}
+	if len(built) != 2 || built[1].secret != "second" {
+		t.Fatalf("expected provider to be rebuilt with rotated secret")
+	}
+
+	// challenges are cleaned up by the instance that presented them
+	for _, domain := range []string{"a", "b", "c"} {
+		if err := provider.CleanUp(domain+".example.com", "", domain); err != nil {
+			t.Fatalf("CleanUp() error = %v", err)
+		}
+	}
+	if built[0].cleanups != 2 || built[1].cleanups != 1 {
+		t.Errorf("unexpected cleanups: first=%d, second=%d", built[0].cleanups, built[1].cleanups)
+	}
+
+	// errors while reading the secret do not break an already built provider
+	backend.err = errors.New("vault unavailable")
+	if err := provider.Present("d.example.com", "", "d"); err != nil {
+		t.Errorf("Present() error = %v", err)
+	}
+	if built[1].presents != 2 {
+		t.Errorf("expected previously built provider to be used")
+	}
+}
+
+func TestSecretProvider_Present(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend *staticSecretBackend
+		wantErr bool
+	}{
+		{
+			name:    "secret can not be read",
+			backend: &staticSecretBackend{err: errors.New("permission denied")},
+			wantErr: true,
+		},
+		{
+			name:    "mandatory field missing",
+			backend: &staticSecretBackend{data: map[string]interface{}{"token": "value"}},
+			wantErr: true,
+		},
+		{
+			name:    "empty mandatory field",
+			backend: &staticSecretBackend{data: map[string]interface{}{"api_key": ""}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewSecretProvider("hetzner", "dns/hetzner", tt.backend, newHetznerProvider)
+			if err != nil {
+				t.Fatalf("NewSecretProvider() error = %v", err)
+			}
+			if err := provider.Present("example.com", "", "keyAuth"); (err != nil) != tt.wantErr {
+				t.Errorf("Present() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretProvider_instanceMetrics(t *testing.T) {
+	conf := config.DnsProviderConfig{
+		Name:       "cloudflare-lab",
+		Type:       config.DnsProviderCloudflare,
+		Cloudflare: &config.CloudflareConfig{SecretVaultPath: "dns/cloudflare"},
+	}
+	provider, err := BuildDnsProvider(conf, DnsProviderDeps{Secrets: &staticSecretBackend{err: errors.New("permission denied")}})
+	if err != nil {
+		t.Fatalf("BuildDnsProvider() error = %v", err)
+	}
+
+	errs := testutil.ToFloat64(metrics.DnsProviderSecretErrors.WithLabelValues(conf.Name))
+	if err := provider.Present("example.com", "", "keyAuth"); err == nil {
+		t.Fatal("expected error for unreadable secret")
+	}
+
+	// the errors are attributed to the configured instance rather than to the provider type
+	if got := testutil.ToFloat64(metrics.DnsProviderSecretErrors.WithLabelValues(conf.Name)) - errs; got != 1 {
+		t.Errorf("secret errors for %s = %v, want 1", conf.Name, got)
+	}
+}
@@ -0,0 +1,35 @@
+package acme
+
+import (
+	"testing"
+
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+func Test_getCertKeyType(t *testing.T) {
+	tests := []struct {
+		keyType string
+		want    certcrypto.KeyType
+		wantErr bool
+	}{
+		{keyType: "", want: certcrypto.RSA4096},
+		{keyType: config.KeyTypeEc256, want: certcrypto.EC256},
+		{keyType: config.KeyTypeEc384, want: certcrypto.EC384},
+		{keyType: config.KeyTypeRsa2048, want: certcrypto.RSA2048},
+		{keyType: config.KeyTypeRsa3072, want: certcrypto.RSA3072},
+		{keyType: "rsa8192", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keyType, func(t *testing.T) {
+			got, err := getCertKeyType(tt.keyType)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getCertKeyType() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("getCertKeyType() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
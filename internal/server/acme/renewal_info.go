@@ -0,0 +1,98 @@
+package acme
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-acme/lego/v4/acme/api"
+	"github.com/go-acme/lego/v4/certcrypto"
+	"github.com/go-acme/lego/v4/certificate"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+// ErrNoRenewalInfo is returned if the CA does not support ACME Renewal Information (ARI).
+var ErrNoRenewalInfo = errors.New("ca does not support renewal information")
+
+// RenewalInfo is the renewal window suggested by the CA for a certificate.
+type RenewalInfo struct {
+	Start time.Time
+	End   time.Time
+	// ExplanationUrl optionally points to a page that explains the suggested window, e.g. a mass revocation event.
+	ExplanationUrl string
+	// CertId is the ARI identifier of the certificate, the point in time to renew within the window is derived from it.
+	CertId string
+}
+
+// ShouldRenew returns whether the point in time selected within the suggested window has already passed.
+func (info RenewalInfo) ShouldRenew(now time.Time) bool {
+	if !now.Before(info.End) {
+		return true
+	}
+
+	if now.Before(info.Start) {
+		return false
+	}
+
+	return !info.selectedTime().After(now)
+}
+
+// selectedTime returns a point in time within the window that is uniformly distributed across certificates, so
+// renewals of many certificates are spread across the window. It's derived from the certificate and the window instead
+// of being picked on every check, as repeated random picks would favour the start of the window.
+func (info RenewalInfo) selectedTime() time.Time {
+	hash := sha256.New()
+	hash.Write([]byte(info.CertId))
+	_ = binary.Write(hash, binary.BigEndian, info.Start.UnixNano())
+	_ = binary.Write(hash, binary.BigEndian, info.End.UnixNano())
+	sum := hash.Sum(nil)
+
+	window := uint64(info.End.Sub(info.Start))
+	offset := binary.BigEndian.Uint64(sum[:8]) % (window + 1)
+	return info.Start.Add(time.Duration(offset)) // #nosec G115
+}
+
+// GetRenewalInfo queries the renewal information endpoint of the CA for the given cert. If the CA does not support
+// ARI, ErrNoRenewalInfo is returned.
+func (l *GoLego) GetRenewalInfo(cert *certstorage.AcmeCertificate) (*RenewalInfo, error) {
+	x509Cert, err := parseLeafCertificate(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := l.client.Certificate.GetRenewalInfo(certificate.RenewalInfoRequest{Cert: x509Cert})
+	if err != nil {
+		if errors.Is(err, api.ErrNoARI) {
+			return nil, ErrNoRenewalInfo
+		}
+		return nil, fmt.Errorf("could not get renewal info: %w", err)
+	}
+
+	certId, err := certificate.MakeARICertID(x509Cert)
+	if err != nil {
+		return nil, fmt.Errorf("could not build cert id: %w", err)
+	}
+
+	return &RenewalInfo{
+		Start:          info.SuggestedWindow.Start,
+		End:            info.SuggestedWindow.End,
+		ExplanationUrl: info.ExplanationURL,
+		CertId:         certId,
+	}, nil
+}
+
+func parseLeafCertificate(cert *certstorage.AcmeCertificate) (*x509.Certificate, error) {
+	certificates, err := certcrypto.ParsePEMBundle(cert.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse cert: %w", err)
+	}
+
+	if certificates[0].IsCA {
+		return nil, fmt.Errorf("certificate bundle of domain %s starts with a CA certificate", cert.Domain)
+	}
+
+	return certificates[0], nil
+}
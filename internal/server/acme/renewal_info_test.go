@@ -0,0 +1,64 @@
+package acme
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenewalInfo_ShouldRenew(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		info RenewalInfo
+		want bool
+	}{
+		{
+			name: "before window",
+			info: RenewalInfo{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+			want: false,
+		},
+		{
+			name: "after window",
+			info: RenewalInfo{Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)},
+			want: true,
+		},
+		{
+			name: "end of window",
+			info: RenewalInfo{Start: now.Add(-time.Hour), End: now},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.ShouldRenew(now); got != tt.want {
+				t.Errorf("ShouldRenew() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenewalInfo_ShouldRenew_stable(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	info := RenewalInfo{Start: start, End: start.Add(48 * time.Hour), CertId: "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyE"}
+
+	selected := info.selectedTime()
+	if selected.Before(info.Start) || selected.After(info.End) {
+		t.Fatalf("selectedTime() = %v, not within window", selected)
+	}
+
+	// repeated checks must come to the same decision
+	for i := 0; i < 100; i++ {
+		if info.ShouldRenew(selected.Add(-time.Second)) {
+			t.Fatal("ShouldRenew() = true before selected time")
+		}
+		if !info.ShouldRenew(selected) {
+			t.Fatal("ShouldRenew() = false at selected time")
+		}
+	}
+
+	other := info
+	other.CertId = "aYhba4dGQEHhs3uEe6CuLN4ByNQ.AIdlQyF"
+	if other.selectedTime().Equal(selected) {
+		t.Errorf("selectedTime() is the same for different certs")
+	}
+}
@@ -0,0 +1,41 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+// revocationReasons maps the names of the RFC 5280 reason codes to their values.
+var revocationReasons = map[string]uint{
+	"unspecified":          acme.CRLReasonUnspecified,
+	"keycompromise":        acme.CRLReasonKeyCompromise,
+	"cacompromise":         acme.CRLReasonCACompromise,
+	"affiliationchanged":   acme.CRLReasonAffiliationChanged,
+	"superseded":           acme.CRLReasonSuperseded,
+	"cessationofoperation": acme.CRLReasonCessationOfOperation,
+	"certificatehold":      acme.CRLReasonCertificateHold,
+	"removefromcrl":        acme.CRLReasonRemoveFromCRL,
+	"privilegewithdrawn":   acme.CRLReasonPrivilegeWithdrawn,
+	"aacompromise":         acme.CRLReasonAACompromise,
+}
+
+// ParseRevocationReason returns the RFC 5280 reason code for the given name, e.g. "keyCompromise".
+func ParseRevocationReason(name string) (uint, error) {
+	reason, ok := revocationReasons[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown revocation reason %q", name)
+	}
+	return reason, nil
+}
+
+func (l *GoLego) RevokeCert(cert *certstorage.AcmeCertificate, reason uint) error {
+	if cert == nil {
+		return errors.New("empty certificate provided")
+	}
+
+	return l.client.Certificate.RevokeWithReason(cert.Certificate, &reason)
+}
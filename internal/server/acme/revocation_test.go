@@ -0,0 +1,30 @@
+package acme
+
+import "testing"
+
+func TestParseRevocationReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    uint
+		wantErr bool
+	}{
+		{name: "unspecified", want: 0},
+		{name: "keyCompromise", want: 1},
+		{name: "superseded", want: 4},
+		{name: "cessationOfOperation", want: 5},
+		{name: "aACompromise", want: 10},
+		{name: "stolen", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRevocationReason(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRevocationReason() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseRevocationReason() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package acme
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge"
+	legoRfc2136 "github.com/go-acme/lego/v4/providers/dns/rfc2136"
+	"github.com/miekg/dns"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+// Rfc2136VaultKeyTsigSecret is the name of the field that holds the TSIG secret in the secret read from Vault.
+const Rfc2136VaultKeyTsigSecret = "tsig_secret"
+
+func init() {
+	RegisterDnsProvider(config.DnsProviderRfc2136, buildRfc2136)
+}
+
+// rfc2136SecretProvider reads the TSIG secret from the storage and resolves all challenges sequentially, just like
+// the wrapped provider.
+type rfc2136SecretProvider struct {
+	*SecretProvider
+	sequenceInterval time.Duration
+}
+
+// Sequential returns the interval between solving the challenges, which are resolved sequentially.
+func (p *rfc2136SecretProvider) Sequential() time.Duration {
+	return p.sequenceInterval
+}
+
+func buildRfc2136(conf config.DnsProviderConfig, deps DnsProviderDeps) (challenge.Provider, error) {
+	if conf.Rfc2136 == nil {
+		return nil, errors.New("no rfc2136 config provided")
+	}
+
+	return NewRfc2136Provider(conf.Name, *conf.Rfc2136, deps.Secrets)
+}
+
+// NewRfc2136Provider builds a provider that solves DNS-01 challenges by sending dynamic updates (RFC2136) to an
+// authoritative nameserver. The name identifies the configured provider instance in logs and metrics.
+func NewRfc2136Provider(name string, conf config.Rfc2136Config, secrets SecretBackend) (challenge.Provider, error) {
+	if len(conf.Nameserver) == 0 {
+		return nil, errors.New("rfc2136: nameserver missing")
+	}
+
+	if conf.UseTsig() && len(conf.TsigSecret) == 0 && len(conf.TsigSecretVaultPath) == 0 {
+		return nil, errors.New("rfc2136: tsig key given but neither tsig secret nor vault path")
+	}
+
+	legoConf := legoRfc2136.NewDefaultConfig()
+	legoConf.Nameserver = conf.Nameserver
+	legoConf.TSIGKey = conf.TsigKey
+	legoConf.TSIGSecret = conf.TsigSecret
+	if len(conf.TsigAlgorithm) > 0 {
+		legoConf.TSIGAlgorithm = dns.Fqdn(conf.TsigAlgorithm)
+	}
+
+	if len(conf.TsigSecretVaultPath) == 0 {
+		return legoRfc2136.NewDNSProviderConfig(legoConf)
+	}
+
+	build := func(secret ProviderSecret) (challenge.Provider, error) {
+		tsigSecret, err := secret.Get(Rfc2136VaultKeyTsigSecret)
+		if err != nil {
+			return nil, err
+		}
+
+		providerConf := *legoConf
+		providerConf.TSIGSecret = tsigSecret
+		return legoRfc2136.NewDNSProviderConfig(&providerConf)
+	}
+
+	provider, err := NewSecretProvider(name, conf.TsigSecretVaultPath, secrets, build)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rfc2136SecretProvider{
+		SecretProvider:   provider,
+		sequenceInterval: legoConf.SequenceInterval,
+	}, nil
+}
@@ -0,0 +1,211 @@
+package acme
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/miekg/dns"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+const (
+	rfc2136Zone       = "example.com."
+	rfc2136TsigKey    = "acmevault."
+	rfc2136TsigSecret = "IwBTJx9wrDp4Y1RyC3H0gA=="
+)
+
+type staticSecretBackend struct {
+	data map[string]interface{}
+	err  error
+}
+
+func (s *staticSecretBackend) ReadSecret(_ string) (map[string]interface{}, error) {
+	return s.data, s.err
+}
+
+func TestRfc2136Provider_Present(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.Rfc2136Config
+		secrets SecretBackend
+		wantErr bool
+	}{
+		{
+			name: "tsig secret from config",
+			conf: config.Rfc2136Config{
+				TsigKey:       rfc2136TsigKey,
+				TsigAlgorithm: "hmac-sha256",
+				TsigSecret:    rfc2136TsigSecret,
+			},
+		},
+		{
+			name: "tsig secret from vault",
+			conf: config.Rfc2136Config{
+				TsigKey:             rfc2136TsigKey,
+				TsigAlgorithm:       "hmac-sha256",
+				TsigSecretVaultPath: "dns/rfc2136",
+			},
+			secrets: &staticSecretBackend{data: map[string]interface{}{Rfc2136VaultKeyTsigSecret: rfc2136TsigSecret}},
+		},
+		{
+			name: "wrong tsig secret",
+			conf: config.Rfc2136Config{
+				TsigKey:       rfc2136TsigKey,
+				TsigAlgorithm: "hmac-sha256",
+				TsigSecret:    "d3Jvbmcgc2VjcmV0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "vault error",
+			conf: config.Rfc2136Config{
+				TsigKey:             rfc2136TsigKey,
+				TsigSecretVaultPath: "dns/rfc2136",
+			},
+			secrets: &staticSecretBackend{err: errors.New("permission denied")},
+			wantErr: true,
+		},
+		{
+			name: "missing field in vault secret",
+			conf: config.Rfc2136Config{
+				TsigKey:             rfc2136TsigKey,
+				TsigSecretVaultPath: "dns/rfc2136",
+			},
+			secrets: &staticSecretBackend{data: map[string]interface{}{"secret": rfc2136TsigSecret}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dns01.ClearFqdnCache()
+			updates := make(chan *dns.Msg, 10)
+			addr := runRfc2136TestServer(t, updates)
+
+			tt.conf.Nameserver = addr
+			provider, err := NewRfc2136Provider("bind", tt.conf, tt.secrets)
+			if err != nil {
+				t.Fatalf("NewRfc2136Provider() error = %v", err)
+			}
+
+			err = provider.Present("sub.example.com", "", "keyAuth")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Present() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			select {
+			case update := <-updates:
+				if len(update.Ns) != 2 {
+					t.Fatalf("expected RRset removal and insert, got %v", update.Ns)
+				}
+				txt, ok := update.Ns[1].(*dns.TXT)
+				if !ok || txt.Hdr.Name != "_acme-challenge.sub."+rfc2136Zone {
+					t.Errorf("unexpected record inserted: %v", update.Ns[1])
+				}
+			default:
+				t.Error("no update received")
+			}
+		})
+	}
+}
+
+func TestNewRfc2136Provider(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.Rfc2136Config
+		secrets SecretBackend
+		wantErr bool
+	}{
+		{
+			name: "no tsig",
+			conf: config.Rfc2136Config{Nameserver: "127.0.0.1"},
+		},
+		{
+			name:    "no nameserver",
+			conf:    config.Rfc2136Config{},
+			wantErr: true,
+		},
+		{
+			name:    "tsig key without secret",
+			conf:    config.Rfc2136Config{Nameserver: "127.0.0.1", TsigKey: rfc2136TsigKey},
+			wantErr: true,
+		},
+		{
+			name:    "vault path without secret backend",
+			conf:    config.Rfc2136Config{Nameserver: "127.0.0.1", TsigKey: rfc2136TsigKey, TsigSecretVaultPath: "dns/rfc2136"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRfc2136Provider("bind", tt.conf, tt.secrets); (err != nil) != tt.wantErr {
+				t.Errorf("NewRfc2136Provider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// runRfc2136TestServer runs an authoritative nameserver for rfc2136Zone that only accepts updates signed with the
+// expected TSIG secret and passes them back on the given channel.
+func runRfc2136TestServer(t *testing.T, updates chan *dns.Msg) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	mux := dns.NewServeMux()
+	mux.HandleFunc(rfc2136Zone, func(w dns.ResponseWriter, req *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(req)
+
+		switch req.Opcode {
+		case dns.OpcodeQuery:
+			soa, _ := dns.NewRR(fmt.Sprintf("%s 120 IN SOA ns1.%s admin.%s 2024010101 28800 7200 2419200 1200", rfc2136Zone, rfc2136Zone, rfc2136Zone))
+			m.Answer = []dns.RR{soa}
+		case dns.OpcodeUpdate:
+			if req.IsTsig() == nil || w.TsigStatus() != nil {
+				m.SetRcode(req, dns.RcodeRefused)
+			} else {
+				updates <- req
+			}
+		}
+
+		if tsig := req.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+			m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	server := &dns.Server{
+		PacketConn: pc,
+		Handler:    mux,
+		TsigSecret: map[string]string{rfc2136TsigKey: rfc2136TsigSecret},
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction {
+			// the default accept func rejects dynamic updates
+			return dns.MsgAccept
+		},
+	}
+
+	started := sync.Mutex{}
+	started.Lock()
+	server.NotifyStartedFunc = started.Unlock
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	started.Lock()
+
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+
+	return pc.LocalAddr().String()
+}
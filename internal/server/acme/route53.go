@@ -12,10 +12,15 @@ import (
 	"github.com/go-acme/lego/v4/challenge"
 	legoRoute53 "github.com/go-acme/lego/v4/providers/dns/route53"
 	"github.com/rs/zerolog/log"
+	acmevaultConfig "github.com/soerenschneider/acmevault/internal/config"
 )
 
 const AwsIamPropagationImpediment = 20 * time.Second
 
+func init() {
+	RegisterDnsProvider(acmevaultConfig.DnsProviderRoute53, buildRoute53)
+}
+
 type DynamicCredentialsProvider struct {
 	vault  AwsDynamicCredentialsBackend
 	expiry time.Time
@@ -25,6 +30,21 @@ type AwsDynamicCredentialsBackend interface {
 	ReadAwsCredentials() (aws.Credentials, error)
 }
 
+// AwsRoleCredentialsBackend generates dynamic AWS credentials for a given role of an AWS secrets engine.
+type AwsRoleCredentialsBackend interface {
+	ReadAwsCredentialsForRole(mountPath, role string) (aws.Credentials, error)
+}
+
+type awsRoleCredentialsBackend struct {
+	backend   AwsRoleCredentialsBackend
+	mountPath string
+	role      string
+}
+
+func (b *awsRoleCredentialsBackend) ReadAwsCredentials() (aws.Credentials, error) {
+	return b.backend.ReadAwsCredentialsForRole(b.mountPath, b.role)
+}
+
 func NewAwsDynamicCredentialsProvider(backend AwsDynamicCredentialsBackend) (aws.CredentialsProvider, error) {
 	if nil == backend {
 		return nil, errors.New("no vault backend provided")
@@ -54,7 +74,36 @@ func (m *DynamicCredentialsProvider) IsExpired() bool {
 	return time.Now().After(m.expiry)
 }
 
-func BuildRoute53DnsProvider(credProvider ...aws.CredentialsProvider) (challenge.Provider, error) {
+func buildRoute53(conf acmevaultConfig.DnsProviderConfig, deps DnsProviderDeps) (challenge.Provider, error) {
+	route53Conf := acmevaultConfig.Route53Config{}
+	if conf.Route53 != nil {
+		route53Conf = *conf.Route53
+	}
+
+	var credProviders []aws.CredentialsProvider
+	if len(route53Conf.AwsRole) > 0 {
+		if deps.AwsRoleCredentials == nil {
+			return nil, errors.New("aws role configured but no backend to read credentials for it")
+		}
+
+		roleBackend := &awsRoleCredentialsBackend{
+			backend:   deps.AwsRoleCredentials,
+			mountPath: route53Conf.AwsMountPath,
+			role:      route53Conf.AwsRole,
+		}
+		credProvider, err := NewAwsDynamicCredentialsProvider(roleBackend)
+		if err != nil {
+			return nil, err
+		}
+		credProviders = append(credProviders, credProvider)
+	} else if deps.AwsCredentials != nil {
+		credProviders = append(credProviders, deps.AwsCredentials)
+	}
+
+	return BuildRoute53DnsProvider(route53Conf, credProviders...)
+}
+
+func BuildRoute53DnsProvider(conf acmevaultConfig.Route53Config, credProvider ...aws.CredentialsProvider) (challenge.Provider, error) {
 	awsConf, err := config.LoadDefaultConfig(context.TODO())
 	if err != nil {
 		return nil, err
@@ -70,5 +119,8 @@ func BuildRoute53DnsProvider(credProvider ...aws.CredentialsProvider) (challenge
 	client := route53.NewFromConfig(awsConf)
 	legoConf := legoRoute53.NewDefaultConfig()
 	legoConf.Client = client
+	if len(conf.HostedZoneId) > 0 {
+		legoConf.HostedZoneID = conf.HostedZoneId
+	}
 	return legoRoute53.NewDNSProviderConfig(legoConf)
 }
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+const (
+	PlanObtain  = "obtain"
+	PlanReissue = "reissue"
+	PlanRenew   = "renew"
+	PlanOk      = "ok"
+	PlanError   = "error"
+)
+
+// PlanResult describes the action that would be taken for the cert of a domain.
+type PlanResult struct {
+	Domain string `json:"domain"`
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// Plan evaluates the stored certs of all domains and reports the actions that would be taken, without contacting the
+// CA. As the renewal window suggested by the CA is not queried, the renewal decision is based on the renewal config.
+func Plan(domains []config.DomainsConfig, storage CertStorage) []PlanResult {
+	results := make([]PlanResult, 0, len(domains))
+	for _, domain := range domains {
+		read, err := storage.ReadPublicCertificateData(domain.Domain)
+		if err != nil && !errors.Is(err, certstorage.ErrNotFound) {
+			results = append(results, PlanResult{Domain: domain.Domain, Action: PlanError, Reason: err.Error()})
+			continue
+		}
+		if read == nil {
+			results = append(results, PlanResult{Domain: domain.Domain, Action: PlanObtain, Reason: "missing"})
+			continue
+		}
+		results = append(results, planCert(domain, read))
+	}
+
+	return results
+}
+
+func planCert(domain config.DomainsConfig, cert *certstorage.AcmeCertificate) PlanResult {
+	result := PlanResult{Domain: domain.Domain}
+	if _, description := reissueReason(domain, cert); len(description) > 0 {
+		result.Action = PlanReissue
+		result.Reason = description
+		return result
+	}
+
+	renew, err := cert.NeedsRenewal(domain.GetRenewalConfig())
+	if err != nil {
+		result.Action = PlanError
+		result.Reason = err.Error()
+		return result
+	}
+
+	result.Action = PlanOk
+	if renew {
+		result.Action = PlanRenew
+	}
+
+	timeLeft, err := cert.GetDurationUntilExpiry()
+	if err == nil {
+		result.Reason = fmt.Sprintf("expires in %d days", int(timeLeft/(24*time.Hour)))
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+func TestPlan(t *testing.T) {
+	day := 24 * time.Hour
+	storage := &MockStorage{}
+	storage.On("ReadPublicCertificateData", "missing.example.com").Return(nil, nil)
+	storage.On("ReadPublicCertificateData", "ok.example.com").Return(&certstorage.AcmeCertificate{
+		Certificate: generateCert(t, 80*day+time.Hour, "ok.example.com"),
+	}, nil)
+	storage.On("ReadPublicCertificateData", "renew.example.com").Return(&certstorage.AcmeCertificate{
+		Certificate: generateCert(t, 10*day+time.Hour, "renew.example.com"),
+	}, nil)
+	storage.On("ReadPublicCertificateData", "sans.example.com").Return(&certstorage.AcmeCertificate{
+		Certificate: generateCert(t, 80*day, "sans.example.com"),
+	}, nil)
+	storage.On("ReadPublicCertificateData", "keytype.example.com").Return(&certstorage.AcmeCertificate{
+		Certificate: generateCert(t, 80*day, "keytype.example.com"),
+	}, nil)
+	storage.On("ReadPublicCertificateData", "broken.example.com").Return(&certstorage.AcmeCertificate{}, nil)
+
+	domains := []config.DomainsConfig{
+		{Domain: "missing.example.com"},
+		{Domain: "ok.example.com"},
+		{Domain: "renew.example.com"},
+		{Domain: "sans.example.com", Sans: []string{"www.sans.example.com"}},
+		{Domain: "keytype.example.com", KeyType: config.KeyTypeEc256},
+		{Domain: "broken.example.com"},
+	}
+
+	got := Plan(domains, storage)
+	want := []PlanResult{
+		{Domain: "missing.example.com", Action: PlanObtain, Reason: "missing"},
+		{Domain: "ok.example.com", Action: PlanOk, Reason: "expires in 80 days"},
+		{Domain: "renew.example.com", Action: PlanRenew, Reason: "expires in 10 days"},
+		{Domain: "sans.example.com", Action: PlanReissue, Reason: "SAN set changed, added www.sans.example.com"},
+		{Domain: "keytype.example.com", Action: PlanReissue, Reason: "key type changed from rsa2048 to ec256"},
+		{Domain: "broken.example.com", Action: PlanError, Reason: "could not determine cert expiry for domain '': could not parse pem block from cert"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan() got = %v, want %v", got, want)
+	}
+}
+
+func TestPlanStorageError(t *testing.T) {
+	storage := &MockStorage{}
+	storage.On("ReadPublicCertificateData", "missing.example.com").Return(nil, fmt.Errorf("could not read cert: %w", certstorage.ErrNotFound))
+	storage.On("ReadPublicCertificateData", "denied.example.com").Return(nil, certstorage.ErrPermissionDenied)
+	storage.On("ReadPublicCertificateData", "outage.example.com").Return(nil, errors.New("connection refused"))
+
+	domains := []config.DomainsConfig{
+		{Domain: "missing.example.com"},
+		{Domain: "denied.example.com"},
+		{Domain: "outage.example.com"},
+	}
+
+	got := Plan(domains, storage)
+	want := []PlanResult{
+		{Domain: "missing.example.com", Action: PlanObtain, Reason: "missing"},
+		{Domain: "denied.example.com", Action: PlanError, Reason: certstorage.ErrPermissionDenied.Error()},
+		{Domain: "outage.example.com", Action: PlanError, Reason: "connection refused"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan() got = %v, want %v", got, want)
+	}
+}
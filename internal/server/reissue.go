@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+const (
+	reissueReasonKeyType = "key_type_changed"
+	reissueReasonSans    = "sans_changed"
+)
+
+// reissueReason returns why the stored cert can not be renewed but needs to be reissued as a short reason that can be
+// used as metric label and a description. Both are empty if the cert can be renewed.
+func reissueReason(domain config.DomainsConfig, cert *certstorage.AcmeCertificate) (string, string) {
+	if len(domain.KeyType) > 0 {
+		keyType, err := cert.GetKeyType()
+		if err == nil && keyType != domain.KeyType {
+			return reissueReasonKeyType, fmt.Sprintf("key type changed from %s to %s", keyType, domain.KeyType)
+		}
+	}
+
+	dnsNames, err := cert.GetDnsNames()
+	if err != nil {
+		return "", ""
+	}
+
+	added, removed := diffDnsNames(dnsNames, append([]string{domain.Domain}, domain.Sans...))
+	if len(added) == 0 && len(removed) == 0 {
+		return "", ""
+	}
+
+	var changes []string
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("added %s", strings.Join(added, ", ")))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("removed %s", strings.Join(removed, ", ")))
+	}
+	return reissueReasonSans, fmt.Sprintf("SAN set changed, %s", strings.Join(changes, "; "))
+}
+
+// diffDnsNames compares the DNS names of a cert with the configured names, ignoring order, case and duplicates. It
+// returns the configured names that are missing in the cert and the names of the cert that are no longer configured.
+func diffDnsNames(certNames, configured []string) (added []string, removed []string) {
+	normalize := func(names []string) []string {
+		ret := make([]string, 0, len(names))
+		for _, name := range names {
+			ret = append(ret, strings.ToLower(name))
+		}
+		slices.Sort(ret)
+		return slices.Compact(ret)
+	}
+
+	certNames = normalize(certNames)
+	configured = normalize(configured)
+	for _, name := range configured {
+		if !slices.Contains(certNames, name) {
+			added = append(added, name)
+		}
+	}
+	for _, name := range certNames {
+		if !slices.Contains(configured, name) {
+			removed = append(removed, name)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_diffDnsNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		certNames   []string
+		configured  []string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{
+			name:       "equal",
+			certNames:  []string{"a.example.com", "b.example.com"},
+			configured: []string{"a.example.com", "b.example.com"},
+		},
+		{
+			name:       "order, case and duplicates",
+			certNames:  []string{"B.example.com", "a.example.com"},
+			configured: []string{"a.example.com", "b.example.com", "a.example.com"},
+		},
+		{
+			name:       "added",
+			certNames:  []string{"a.example.com"},
+			configured: []string{"a.example.com", "b.example.com"},
+			wantAdded:  []string{"b.example.com"},
+		},
+		{
+			name:        "removed",
+			certNames:   []string{"a.example.com", "b.example.com"},
+			configured:  []string{"a.example.com"},
+			wantRemoved: []string{"b.example.com"},
+		},
+		{
+			name:        "replaced",
+			certNames:   []string{"a.example.com", "b.example.com"},
+			configured:  []string{"a.example.com", "*.example.com"},
+			wantAdded:   []string{"*.example.com"},
+			wantRemoved: []string{"b.example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffDnsNames(tt.certNames, tt.configured)
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("diffDnsNames() added = %v, want %v", added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("diffDnsNames() removed = %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package server
+
+const (
+	ActionObtained = "obtained"
+	ActionReissued = "reissued"
+	ActionRenewed  = "renewed"
+	ActionNone     = "none"
+	ActionFailed   = "failed"
+	ActionSkipped  = "skipped"
+)
+
+// CheckResult is the outcome of checking the cert of a single domain.
+type CheckResult struct {
+	Domain string `json:"domain"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/soerenschneider/acmevault/internal/config"
@@ -65,81 +66,162 @@ func (c *AcmeVault) getGoroutineCount() int {
 	return min(maxConcurrentGoRoutines, len(c.domains))
 }
 
-func (c *AcmeVault) CheckCerts(ctx context.Context, wg *sync.WaitGroup) error {
+// CheckCerts checks the certs of all configured domains and returns once all domains have been processed or the
+// context has been cancelled.
+func (c *AcmeVault) CheckCerts(ctx context.Context, wg *sync.WaitGroup) ([]CheckResult, error) {
 	metrics.ServerLatestIterationTimestamp.SetToCurrentTime()
-	ch := make(chan config.DomainsConfig, len(c.domains))
-	for _, data := range c.domains {
-		ch <- data
+	results := make([]CheckResult, len(c.domains))
+	ch := make(chan int, len(c.domains))
+	for i, domain := range c.domains {
+		results[i] = CheckResult{Domain: domain.Domain, Action: ActionSkipped}
+		ch <- i
 	}
+	close(ch)
 
-	mutex := sync.RWMutex{}
-	stop := false
-	wg.Add(1)
-
-	go func() {
-		<-ctx.Done()
-		mutex.Lock()
-		stop = true
-		mutex.Unlock()
-		wg.Done()
-	}()
-
+	mutex := sync.Mutex{}
+	workers := sync.WaitGroup{}
 	var errs error
 
 	for i := 0; i < c.getGoroutineCount(); i++ {
 		wg.Add(1)
+		workers.Add(1)
 		go func() {
-			for domain := range ch {
-				mutex.RLock()
-				if stop {
-					mutex.RUnlock()
+			defer wg.Done()
+			defer workers.Done()
+			for idx := range ch {
+				if ctx.Err() != nil {
 					return
 				}
-				mutex.RUnlock()
 
-				if err := c.obtainAndHandleCert(domain); err != nil {
-					mutex.Lock()
+				action, err := c.obtainAndHandleCert(c.domains[idx])
+				mutex.Lock()
+				results[idx].Action = action
+				if err != nil {
+					results[idx].Action = ActionFailed
+					results[idx].Error = err.Error()
 					errs = multierr.Append(errs, err)
-					mutex.Unlock()
 				}
+				mutex.Unlock()
 			}
 		}()
-		wg.Done()
 	}
 
-	return errs
+	workers.Wait()
+	return results, errs
 }
 
-func (c *AcmeVault) obtainAndHandleCert(domain config.DomainsConfig) error {
+func (c *AcmeVault) obtainAndHandleCert(domain config.DomainsConfig) (string, error) {
 	read, err := c.certStorage.ReadPublicCertificateData(domain.Domain)
 	if err != nil || read == nil {
 		log.Error().Str("domain", domain.Domain).Err(err).Msg("Error reading cert data from storage")
 		log.Info().Str("domain", domain.Domain).Msg("Trying to obtain cert from configured ACME provider")
-		obtained, err := c.acmeClient.ObtainCert(domain)
-		metrics.CertificatesRetrieved.Inc()
-		if err != nil {
-			metrics.CertificatesRetrievalErrors.Inc()
-			return fmt.Errorf("obtaining cert for domain %s failed: %v", domain.Domain, err)
-		}
-		return handleReceivedCert(obtained, c.certStorage)
+		return ActionObtained, c.obtainCert(domain)
 	}
 
 	log.Info().Str("domain", domain.Domain).Msg("Read cert data for domain")
-	renewCert, err := read.NeedsRenewal()
+	if reason, description := reissueReason(domain, read); len(reason) > 0 {
+		log.Info().Str("domain", domain.Domain).Str("reason", description).Msg("Reissuing cert")
+		metrics.CertificatesReissued.WithLabelValues(domain.Domain, reason).Inc()
+		return ActionReissued, c.obtainCert(domain)
+	}
+
+	renewCert, err := c.needsRenewal(domain, read)
 	if err != nil {
 		log.Warn().Str("domain", domain.Domain).Msg("Could not determine cert lifetime")
 	}
 
 	if renewCert {
-		renewed, err := c.acmeClient.RenewCert(read)
+		keyRenewals := 0
+		if domain.ReuseKey(read.KeyRenewals) {
+			full, err := c.certStorage.ReadFullCertificateData(domain.Domain)
+			if err != nil || full == nil {
+				metrics.CertificatesRenewErrors.Inc()
+				return ActionRenewed, fmt.Errorf("could not read private key to reuse it for domain %s: %v", domain.Domain, err)
+			}
+			log.Info().Str("domain", domain.Domain).Int("key_renewals", read.KeyRenewals).Msg("Reusing private key for renewal")
+			read = full
+			keyRenewals = read.KeyRenewals + 1
+		}
+
+		renewed, err := c.acmeClient.RenewCert(domain, read)
 		metrics.CertificatesRenewals.Inc()
 		if err != nil {
 			metrics.CertificatesRenewErrors.Inc()
-			return fmt.Errorf("renewing cert failed for domain %s: %v", domain, err)
+			return ActionRenewed, fmt.Errorf("renewing cert failed for domain %s: %v", domain, err)
+		}
+		if renewed != nil {
+			renewed.KeyRenewals = keyRenewals
 		}
-		return handleReceivedCert(renewed, c.certStorage)
+		return ActionRenewed, handleReceivedCert(renewed, c.certStorage)
 	}
-	return nil
+	return ActionNone, nil
+}
+
+// needsRenewal decides whether the cert should be renewed based on the renewal window suggested by the CA, falling back
+// to the renewal config of the domain if the CA does not support ARI.
+func (c *AcmeVault) needsRenewal(domain config.DomainsConfig, cert *certstorage.AcmeCertificate) (bool, error) {
+	if expiry, err := cert.GetExpiryTimestamp(); err == nil {
+		metrics.CertServerExpiryTimestamp.WithLabelValues(cert.Domain).Set(float64(expiry.Unix()))
+	}
+
+	info, err := c.acmeClient.GetRenewalInfo(cert)
+	if err == nil {
+		metrics.CertRenewalWindowStart.WithLabelValues(cert.Domain).Set(float64(info.Start.Unix()))
+		if len(info.ExplanationUrl) > 0 {
+			log.Warn().Str("domain", cert.Domain).Str("explanation", info.ExplanationUrl).Msg("CA provided explanation for renewal window")
+		}
+		renew := info.ShouldRenew(time.Now().UTC())
+		if renew {
+			log.Info().Str("domain", cert.Domain).Time("window_start", info.Start).Time("window_end", info.End).Msg("Renewing cert within window suggested by CA")
+		}
+		return renew, nil
+	}
+
+	if errors.Is(err, acme.ErrNoRenewalInfo) {
+		log.Debug().Str("domain", cert.Domain).Msg("CA does not support ARI, using renewal config")
+	} else {
+		log.Warn().Str("domain", cert.Domain).Err(err).Msg("Could not get renewal info, using renewal config")
+	}
+	return cert.NeedsRenewal(domain.GetRenewalConfig())
+}
+
+// RevokeCert revokes the stored cert of the given domain and obtains a new cert with a fresh private key afterwards.
+func (c *AcmeVault) RevokeCert(domain string, reason uint) error {
+	var domainConfig *config.DomainsConfig
+	for i := range c.domains {
+		if c.domains[i].Domain == domain {
+			domainConfig = &c.domains[i]
+			break
+		}
+	}
+	if domainConfig == nil {
+		return fmt.Errorf("domain %s is not configured", domain)
+	}
+
+	read, err := c.certStorage.ReadPublicCertificateData(domain)
+	if err != nil || read == nil {
+		return fmt.Errorf("could not read cert data for domain %s: %v", domain, err)
+	}
+
+	log.Info().Str("domain", domain).Uint("reason", reason).Msg("Revoking cert")
+	metrics.CertificatesRevocations.Inc()
+	if err := c.acmeClient.RevokeCert(read, reason); err != nil {
+		metrics.CertificatesRevokeErrors.Inc()
+		return fmt.Errorf("revoking cert for domain %s failed: %v", domain, err)
+	}
+
+	log.Info().Str("domain", domain).Msg("Revoked cert, obtaining new cert")
+	return c.obtainCert(*domainConfig)
+}
+
+func (c *AcmeVault) obtainCert(domain config.DomainsConfig) error {
+	obtained, err := c.acmeClient.ObtainCert(domain)
+	metrics.CertificatesRetrieved.Inc()
+	if err != nil {
+		metrics.CertificatesRetrievalErrors.Inc()
+		return fmt.Errorf("obtaining cert for domain %s failed: %v", domain.Domain, err)
+	}
+	return handleReceivedCert(obtained, c.certStorage)
 }
 
 func handleReceivedCert(cert *certstorage.AcmeCertificate, storage CertStorage) error {
@@ -148,10 +230,10 @@ func handleReceivedCert(cert *certstorage.AcmeCertificate, storage CertStorage)
 	}
 
 	expiry, err := cert.GetExpiryTimestamp()
-	if err != nil {
+	if err == nil {
 		metrics.CertServerExpiryTimestamp.WithLabelValues(cert.Domain).Set(float64(expiry.Unix()))
 	} else {
-		metrics.CertErrors.WithLabelValues(cert.Domain, "unknown-expiry")
+		metrics.CertErrors.WithLabelValues(cert.Domain, "unknown-expiry").Inc()
 	}
 
 	err = storage.WriteCertificate(cert)
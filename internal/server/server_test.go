@@ -1,10 +1,23 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-acme/lego/v4/registration"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/internal/metrics"
+	"github.com/soerenschneider/acmevault/internal/server/acme"
 	"github.com/soerenschneider/acmevault/pkg/certstorage"
 	"github.com/stretchr/testify/mock"
 )
@@ -21,14 +34,279 @@ func TestServerHappyPathRenewal(t *testing.T) {
 	old := &certstorage.AcmeCertificate{}
 	new := &certstorage.AcmeCertificate{}
 	certStorage.On("ReadPublicCertificateData", mock.Anything).Return(old, nil)
-	dealer.On("RenewCert").Return(new, nil)
+	dealer.On("GetRenewalInfo", mock.Anything).Return(nil, acme.ErrNoRenewalInfo)
+	dealer.On("RenewCert", old).Return(new, nil)
 	certStorage.On("WriteCertificate", new).Return(nil)
-	err := server.obtainAndHandleCert(server.domains[0])
+	_, err := server.obtainAndHandleCert(server.domains[0])
 	if err != nil {
 		t.Fail()
 	}
 }
 
+func TestServerReissueOnKeyTypeChange(t *testing.T) {
+	dealer := &MockAcmeDealer{}
+	certStorage := &MockStorage{}
+	server := AcmeVault{
+		acmeClient:  dealer,
+		certStorage: certStorage,
+		domains:     []config.DomainsConfig{{Domain: "example.com", KeyType: config.KeyTypeEc256}},
+	}
+
+	old := &certstorage.AcmeCertificate{
+		Domain:      "example.com",
+		Certificate: generateCert(t, 90*24*time.Hour, "example.com"),
+	}
+	new := &certstorage.AcmeCertificate{}
+	certStorage.On("ReadPublicCertificateData", mock.Anything).Return(old, nil)
+	dealer.On("ObtainCert").Return(new, nil)
+	certStorage.On("WriteCertificate", new).Return(nil)
+	if _, err := server.obtainAndHandleCert(server.domains[0]); err != nil {
+		t.Fatal(err)
+	}
+	dealer.AssertNotCalled(t, "RenewCert")
+	dealer.AssertCalled(t, "ObtainCert")
+}
+
+func TestServerReissueOnSanChange(t *testing.T) {
+	dealer := &MockAcmeDealer{}
+	certStorage := &MockStorage{}
+	server := AcmeVault{
+		acmeClient:  dealer,
+		certStorage: certStorage,
+		domains:     []config.DomainsConfig{{Domain: "example.com", Sans: []string{"www.example.com"}}},
+	}
+
+	old := &certstorage.AcmeCertificate{
+		Domain:      "example.com",
+		Certificate: generateCert(t, 80*24*time.Hour, "example.com"),
+	}
+	new := &certstorage.AcmeCertificate{}
+	certStorage.On("ReadPublicCertificateData", mock.Anything).Return(old, nil)
+	dealer.On("ObtainCert").Return(new, nil)
+	certStorage.On("WriteCertificate", new).Return(nil)
+
+	before := testutil.ToFloat64(metrics.CertificatesReissued.WithLabelValues("example.com", reissueReasonSans))
+	action, err := server.obtainAndHandleCert(server.domains[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != ActionReissued {
+		t.Errorf("expected action %q, got %q", ActionReissued, action)
+	}
+	dealer.AssertNotCalled(t, "GetRenewalInfo", mock.Anything)
+	dealer.AssertCalled(t, "ObtainCert")
+
+	after := testutil.ToFloat64(metrics.CertificatesReissued.WithLabelValues("example.com", reissueReasonSans))
+	if after-before != 1 {
+		t.Errorf("expected reissue metric to be incremented")
+	}
+}
+
+func TestServerRenewalReusesKey(t *testing.T) {
+	dealer := &MockAcmeDealer{}
+	certStorage := &MockStorage{}
+	server := AcmeVault{
+		acmeClient:  dealer,
+		certStorage: certStorage,
+		domains:     []config.DomainsConfig{{Domain: "example.com", KeyPolicy: config.KeyPolicyReuse}},
+	}
+
+	cert := generateCert(t, 24*time.Hour, "example.com")
+	old := &certstorage.AcmeCertificate{Domain: "example.com", Certificate: cert, KeyRenewals: 1}
+	full := &certstorage.AcmeCertificate{Domain: "example.com", Certificate: cert, KeyRenewals: 1, PrivateKey: []byte("key")}
+	new := &certstorage.AcmeCertificate{Domain: "example.com"}
+	certStorage.On("ReadPublicCertificateData", mock.Anything).Return(old, nil)
+	certStorage.On("ReadFullCertificateData", mock.Anything).Return(full, nil)
+	dealer.On("GetRenewalInfo", mock.Anything).Return(nil, acme.ErrNoRenewalInfo)
+	dealer.On("RenewCert", full).Return(new, nil)
+	certStorage.On("WriteCertificate", new).Return(nil)
+	if _, err := server.obtainAndHandleCert(server.domains[0]); err != nil {
+		t.Fatal(err)
+	}
+	dealer.AssertCalled(t, "RenewCert", full)
+	if new.KeyRenewals != 2 {
+		t.Errorf("expected key renewals to be 2, got %d", new.KeyRenewals)
+	}
+}
+
+func TestServerRenewalInfo(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		validity  time.Duration
+		info      *acme.RenewalInfo
+		infoErr   error
+		wantRenew bool
+	}{
+		{
+			name:      "window passed",
+			validity:  60 * 24 * time.Hour,
+			info:      &acme.RenewalInfo{Start: now.Add(-48 * time.Hour), End: now.Add(-24 * time.Hour)},
+			wantRenew: true,
+		},
+		{
+			name:      "window in future",
+			validity:  24 * time.Hour,
+			info:      &acme.RenewalInfo{Start: now.Add(24 * time.Hour), End: now.Add(48 * time.Hour)},
+			wantRenew: false,
+		},
+		{
+			name:      "no ari, fallback to renewal config",
+			validity:  24 * time.Hour,
+			infoErr:   acme.ErrNoRenewalInfo,
+			wantRenew: true,
+		},
+		{
+			name:      "ari error, fallback to renewal config",
+			validity:  80 * 24 * time.Hour,
+			infoErr:   errors.New("connection refused"),
+			wantRenew: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dealer := &MockAcmeDealer{}
+			server := AcmeVault{acmeClient: dealer}
+
+			cert := &certstorage.AcmeCertificate{Domain: "example.com", Certificate: generateCert(t, tt.validity, "example.com")}
+			if tt.info != nil {
+				dealer.On("GetRenewalInfo", cert).Return(tt.info, nil)
+			} else {
+				dealer.On("GetRenewalInfo", cert).Return(nil, tt.infoErr)
+			}
+
+			metrics.CertServerExpiryTimestamp.WithLabelValues(cert.Domain).Set(0)
+			got, err := server.needsRenewal(config.DomainsConfig{Domain: "example.com"}, cert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantRenew {
+				t.Errorf("needsRenewal() = %v, want %v", got, tt.wantRenew)
+			}
+
+			// the expiry is exported regardless of whether the CA suggested a renewal window
+			expiry, _ := cert.GetExpiryTimestamp()
+			if got := testutil.ToFloat64(metrics.CertServerExpiryTimestamp.WithLabelValues(cert.Domain)); got != float64(expiry.Unix()) {
+				t.Errorf("expiry timestamp = %v, want %v", got, expiry.Unix())
+			}
+		})
+	}
+}
+
+func TestHandleReceivedCert(t *testing.T) {
+	storage := &MockStorage{}
+	cert := &certstorage.AcmeCertificate{Domain: "received.example.com", Certificate: generateCert(t, 24*time.Hour, "received.example.com")}
+	storage.On("WriteCertificate", cert).Return(nil)
+
+	if err := handleReceivedCert(cert, storage); err != nil {
+		t.Fatalf("handleReceivedCert() error = %v", err)
+	}
+
+	expiry, _ := cert.GetExpiryTimestamp()
+	if got := testutil.ToFloat64(metrics.CertServerExpiryTimestamp.WithLabelValues(cert.Domain)); got != float64(expiry.Unix()) {
+		t.Errorf("expiry timestamp = %v, want %v", got, expiry.Unix())
+	}
+}
+
+func TestServerRevokeCert(t *testing.T) {
+	dealer := &MockAcmeDealer{}
+	certStorage := &MockStorage{}
+	server := AcmeVault{
+		acmeClient:  dealer,
+		certStorage: certStorage,
+		domains:     []config.DomainsConfig{{Domain: "example.com", KeyPolicy: config.KeyPolicyReuse}},
+	}
+
+	old := &certstorage.AcmeCertificate{Domain: "example.com", Certificate: generateCert(t, 60*24*time.Hour, "example.com")}
+	new := &certstorage.AcmeCertificate{Domain: "example.com"}
+	certStorage.On("ReadPublicCertificateData", "example.com").Return(old, nil)
+	dealer.On("RevokeCert", old, uint(1)).Return(nil)
+	dealer.On("ObtainCert").Return(new, nil)
+	certStorage.On("WriteCertificate", new).Return(nil)
+
+	if err := server.RevokeCert("example.com", 1); err != nil {
+		t.Fatal(err)
+	}
+	dealer.AssertCalled(t, "RevokeCert", old, uint(1))
+	dealer.AssertCalled(t, "ObtainCert")
+
+	if err := server.RevokeCert("unknown.example.com", 1); err == nil {
+		t.Error("expected error for unknown domain")
+	}
+}
+
+func TestServerRevokeCertFailed(t *testing.T) {
+	dealer := &MockAcmeDealer{}
+	certStorage := &MockStorage{}
+	server := AcmeVault{
+		acmeClient:  dealer,
+		certStorage: certStorage,
+		domains:     []config.DomainsConfig{{Domain: "example.com"}},
+	}
+
+	old := &certstorage.AcmeCertificate{Domain: "example.com"}
+	certStorage.On("ReadPublicCertificateData", "example.com").Return(old, nil)
+	dealer.On("RevokeCert", old, uint(4)).Return(errors.New("unauthorized"))
+
+	if err := server.RevokeCert("example.com", 4); err == nil {
+		t.Fatal("expected error")
+	}
+	dealer.AssertNotCalled(t, "ObtainCert")
+}
+
+func TestServerCheckCerts(t *testing.T) {
+	dealer := &MockAcmeDealer{}
+	certStorage := &MockStorage{}
+	server := AcmeVault{
+		acmeClient:  dealer,
+		certStorage: certStorage,
+		domains: []config.DomainsConfig{
+			{Domain: "valid.example.com"},
+			{Domain: "missing.example.com"},
+		},
+	}
+
+	valid := &certstorage.AcmeCertificate{Domain: "valid.example.com", Certificate: generateCert(t, 80*24*time.Hour, "valid.example.com")}
+	certStorage.On("ReadPublicCertificateData", "valid.example.com").Return(valid, nil)
+	certStorage.On("ReadPublicCertificateData", "missing.example.com").Return(nil, nil)
+	dealer.On("GetRenewalInfo", valid).Return(nil, acme.ErrNoRenewalInfo)
+	dealer.On("ObtainCert").Return(nil, errors.New("rate limited"))
+
+	results, err := server.CheckCerts(context.Background(), &sync.WaitGroup{})
+	if err == nil {
+		t.Error("expected error")
+	}
+
+	want := []CheckResult{
+		{Domain: "valid.example.com", Action: ActionNone},
+		{Domain: "missing.example.com", Action: ActionFailed, Error: "obtaining cert for domain missing.example.com failed: rate limited"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("CheckCerts() got = %v, want %v", results, want)
+	}
+}
+
+// generateCert returns a PEM encoded self-signed RSA certificate for the given DNS names with a lifetime of 90 days
+// that expires after the given duration.
+func generateCert(t *testing.T, validity time.Duration, dnsNames ...string) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(validity - 90*24*time.Hour),
+		NotAfter:     time.Now().Add(validity),
+		DNSNames:     dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
 type MockAcmeDealer struct {
 	mock.Mock
 }
@@ -49,8 +327,8 @@ func (m *MockAcmeDealer) ObtainCert(domains config.DomainsConfig) (*certstorage.
 	return args.Get(0).(*certstorage.AcmeCertificate), args.Error(1)
 }
 
-func (m *MockAcmeDealer) RenewCert(cert *certstorage.AcmeCertificate) (*certstorage.AcmeCertificate, error) {
-	args := m.Called()
+func (m *MockAcmeDealer) RenewCert(domain config.DomainsConfig, cert *certstorage.AcmeCertificate) (*certstorage.AcmeCertificate, error) {
+	args := m.Called(cert)
 	if nil == args.Get(0) {
 		return nil, args.Error(1)
 	}
@@ -58,6 +336,20 @@ func (m *MockAcmeDealer) RenewCert(cert *certstorage.AcmeCertificate) (*certstor
 	return args.Get(0).(*certstorage.AcmeCertificate), args.Error(1)
 }
 
+func (m *MockAcmeDealer) GetRenewalInfo(cert *certstorage.AcmeCertificate) (*acme.RenewalInfo, error) {
+	args := m.Called(cert)
+	if nil == args.Get(0) {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*acme.RenewalInfo), args.Error(1)
+}
+
+func (m *MockAcmeDealer) RevokeCert(cert *certstorage.AcmeCertificate, reason uint) error {
+	args := m.Called(cert, reason)
+	return args.Error(0)
+}
+
 type MockStorage struct {
 	mock.Mock
 }
@@ -76,7 +368,7 @@ func (m *MockStorage) ReadPublicCertificateData(domain string) (*certstorage.Acm
 	args := m.Called(domain)
 
 	if args.Get(0) == nil {
-		return nil, nil
+		return nil, args.Error(1)
 	}
 	return args.Get(0).(*certstorage.AcmeCertificate), args.Error(1)
 }
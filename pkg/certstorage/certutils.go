@@ -8,6 +8,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-acme/lego/v4/certcrypto"
@@ -21,11 +22,17 @@ const (
 	vaultCertKeyUrl        = "url"
 	vaultCertKeyStableUrl  = "stable_url"
 	vaultVersion           = "version"
+	// vaultCertKeyKeyRenewals is the number of renewals the certificate's private key has been reused for.
+	vaultCertKeyKeyRenewals = "key_renewals"
 
 	VaultAccountKeyUri     = "uri"
 	VaultAccountKeyEmail   = "email"
 	VaultAccountKeyAccount = "account"
 	VaultAccountKeyKey     = "key"
+	// VaultAccountKeyDirectory is the ACME directory URL of the CA the account is registered at.
+	VaultAccountKeyDirectory = "directory"
+	// VaultAccountKeyEabKeyId is the key id of the external account binding used to register the account.
+	VaultAccountKeyEabKeyId = "eab_kid"
 )
 
 type CertMetadata struct {
@@ -47,6 +54,10 @@ func CertToMap(res *AcmeCertificate) map[string]interface{} {
 		vaultVersion:          "v1",
 	}
 
+	if res.KeyRenewals > 0 {
+		data[vaultCertKeyKeyRenewals] = res.KeyRenewals
+	}
+
 	if res.PrivateKey != nil {
 		data[vaultCertKeyPrivateKey] = res.PrivateKey
 	}
@@ -64,6 +75,14 @@ func MapToCert(data map[string]interface{}) (*AcmeCertificate, error) {
 	res.CertStableURL = fmt.Sprint(data[vaultCertKeyStableUrl])
 	res.CertURL = fmt.Sprint(data[vaultCertKeyUrl])
 
+	if keyRenewals, ok := data[vaultCertKeyKeyRenewals]; ok {
+		parsed, err := strconv.Atoi(fmt.Sprint(keyRenewals))
+		if err != nil {
+			return nil, fmt.Errorf("can not parse key renewals: %v", err)
+		}
+		res.KeyRenewals = parsed
+	}
+
 	_, ok := data[vaultCertKeyPrivateKey]
 	if ok {
 		privRaw := fmt.Sprintf("%s", data[vaultCertKeyPrivateKey])
@@ -100,12 +119,17 @@ func ConvertToPem(privateKey crypto.PrivateKey) (string, error) {
 
 func FromPem(keyData []byte) (crypto.PrivateKey, error) {
 	keyBlock, _ := pem.Decode(keyData)
+	if keyBlock == nil {
+		return nil, errors.New("could not parse pem block from private key")
+	}
 
 	switch keyBlock.Type {
 	case "RSA PRIVATE KEY":
 		return x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
 	case "EC PRIVATE KEY":
 		return x509.ParseECPrivateKey(keyBlock.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
 	}
 
 	return nil, errors.New("unknown private key type")
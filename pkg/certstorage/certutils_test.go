@@ -1,8 +1,15 @@
 package certstorage
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -140,6 +147,43 @@ func TestMapToCert(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "key renewals",
+			args: args{
+				map[string]interface{}{
+					vaultCertKeyIssuer:      dummyIssuer,
+					vaultCertKeyUrl:         "url",
+					vaultCertKeyCert:        dummyCert,
+					vaultCertKeyDomain:      "domain.tld",
+					vaultCertKeyStableUrl:   "stable url",
+					vaultCertKeyKeyRenewals: json.Number("2"),
+				},
+			},
+			want: &AcmeCertificate{
+				Domain:            "domain.tld",
+				CertURL:           "url",
+				CertStableURL:     "stable url",
+				Certificate:       []byte(decodedCert),
+				IssuerCertificate: []byte(decodedIssuer),
+				KeyRenewals:       2,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid key renewals",
+			args: args{
+				map[string]interface{}{
+					vaultCertKeyIssuer:      dummyIssuer,
+					vaultCertKeyUrl:         "url",
+					vaultCertKeyCert:        dummyCert,
+					vaultCertKeyDomain:      "domain.tld",
+					vaultCertKeyStableUrl:   "stable url",
+					vaultCertKeyKeyRenewals: "many",
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name: "invalid csr",
 			args: args{
@@ -243,3 +287,60 @@ func TestMapToCert(t *testing.T) {
 		})
 	}
 }
+
+func TestFromPem(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDer, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8Der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaDer, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(dummyRsaPrivate, "\n", ""))
+
+	tests := []struct {
+		name    string
+		keyData []byte
+		wantErr bool
+	}{
+		{
+			name:    "rsa",
+			keyData: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: rsaDer}),
+		},
+		{
+			name:    "ec",
+			keyData: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDer}),
+		},
+		{
+			name:    "pkcs8",
+			keyData: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Der}),
+		},
+		{
+			name:    "unknown type",
+			keyData: pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: pkcs8Der}),
+			wantErr: true,
+		},
+		{
+			name:    "no pem",
+			keyData: []byte("garbage"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromPem(tt.keyData)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FromPem() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got == nil {
+				t.Errorf("FromPem() returned no key")
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package filesystem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+const (
+	lockFile = ".lock"
+
+	certFile     = "cert.pem"
+	issuerFile   = "issuer.pem"
+	keyFile      = "key.pem"
+	metadataFile = "metadata.json"
+
+	defaultAwsRole = "credentials"
+
+	dirPermissions    = 0750
+	publicPermissions = 0644
+	secretPermissions = 0600
+)
+
+// FilesystemBackend stores certs and accounts in a directory on the local filesystem.
+//
+// The directory is laid out as follows:
+//
+//	certs/<domain>/{cert.pem,issuer.pem,key.pem,metadata.json}
+//	accounts/<acme directory host>/<email>.json
+//	secrets/<path>.json
+//	aws/<role>.json
+type FilesystemBackend struct {
+	dir string
+}
+
+type certMetadata struct {
+	Domain        string `json:"domain"`
+	CertURL       string `json:"url"`
+	CertStableURL string `json:"stable_url"`
+	KeyRenewals   int    `json:"key_renewals,omitempty"`
+}
+
+type accountData struct {
+	Email     string       `json:"email"`
+	Uri       string       `json:"uri"`
+	Account   acme.Account `json:"account"`
+	Key       string       `json:"key"`
+	Directory string       `json:"directory"`
+	EabKeyId  string       `json:"eab_kid,omitempty"`
+}
+
+type awsCredentials struct {
+	AccessKey    string `json:"access_key"`
+	SecretKey    string `json:"secret_key"`
+	SessionToken string `json:"session_token,omitempty"`
+}
+
+func NewFilesystemBackend(conf config.FilesystemConfig) (*FilesystemBackend, error) {
+	if len(conf.Path) == 0 {
+		return nil, errors.New("no path given")
+	}
+
+	if err := os.MkdirAll(conf.Path, dirPermissions); err != nil {
+		return nil, fmt.Errorf("could not create directory %s: %w", conf.Path, err)
+	}
+
+	return &FilesystemBackend{dir: conf.Path}, nil
+}
+
+func (b *FilesystemBackend) Authenticate() error {
+	return nil
+}
+
+func (b *FilesystemBackend) Logout() error {
+	return nil
+}
+
+func (b *FilesystemBackend) WriteCertificate(resource *certstorage.AcmeCertificate) error {
+	if resource == nil {
+		return errors.New("empty certificate provided")
+	}
+
+	unlock, err := b.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	certDir, err := b.getCertDir(resource.Domain)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(certDir, dirPermissions); err != nil {
+		return fmt.Errorf("could not create directory for domain %s: %w", resource.Domain, err)
+	}
+
+	metadata, err := json.Marshal(certMetadata{
+		Domain:        resource.Domain,
+		CertURL:       resource.CertURL,
+		CertStableURL: resource.CertStableURL,
+		KeyRenewals:   resource.KeyRenewals,
+	})
+	if err != nil {
+		return err
+	}
+
+	// write the private key first, so the cert is never newer than its key
+	if resource.PrivateKey != nil {
+		if err := writeFileAtomic(filepath.Join(certDir, keyFile), resource.PrivateKey, secretPermissions); err != nil {
+			return fmt.Errorf("could not write private key for domain %s: %w", resource.Domain, err)
+		}
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{issuerFile, resource.IssuerCertificate},
+		{certFile, resource.Certificate},
+		{metadataFile, metadata},
+	}
+	for _, file := range files {
+		if err := writeFileAtomic(filepath.Join(certDir, file.name), file.data, publicPermissions); err != nil {
+			return fmt.Errorf("could not write certificate data for domain %s: %w", resource.Domain, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *FilesystemBackend) ReadPublicCertificateData(domain string) (*certstorage.AcmeCertificate, error) {
+	unlock, err := b.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return b.readPublicCertificateData(domain)
+}
+
+func (b *FilesystemBackend) ReadFullCertificateData(domain string) (*certstorage.AcmeCertificate, error) {
+	unlock, err := b.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	cert, err := b.readPublicCertificateData(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	certDir, err := b.getCertDir(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	cert.PrivateKey, err = readFile(filepath.Join(certDir, keyFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key for domain %s: %w", domain, err)
+	}
+
+	return cert, nil
+}
+
+func (b *FilesystemBackend) readPublicCertificateData(domain string) (*certstorage.AcmeCertificate, error) {
+	certDir, err := b.getCertDir(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := readFile(filepath.Join(certDir, metadataFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not read cert data for domain %s: %w", domain, err)
+	}
+
+	var metadata certMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("could not parse cert metadata for domain %s: %w", domain, err)
+	}
+
+	cert := &certstorage.AcmeCertificate{
+		Domain:        metadata.Domain,
+		CertURL:       metadata.CertURL,
+		CertStableURL: metadata.CertStableURL,
+		KeyRenewals:   metadata.KeyRenewals,
+	}
+
+	cert.Certificate, err = readFile(filepath.Join(certDir, certFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not read cert for domain %s: %w", domain, err)
+	}
+
+	cert.IssuerCertificate, err = readFile(filepath.Join(certDir, issuerFile))
+	if err != nil {
+		return nil, fmt.Errorf("could not read issuer cert for domain %s: %w", domain, err)
+	}
+
+	return cert, nil
+}
+
+func (b *FilesystemBackend) WriteAccount(account certstorage.AcmeAccount) error {
+	if account.Registration == nil {
+		return errors.New("account is not registered")
+	}
+
+	key, err := certstorage.ConvertToPem(account.Key)
+	if err != nil {
+		return fmt.Errorf("could not encode account key: %w", err)
+	}
+
+	data, err := json.MarshalIndent(accountData{
+		Email:     account.Email,
+		Uri:       account.Registration.URI,
+		Account:   account.Registration.Body,
+		Key:       key,
+		Directory: account.Directory,
+		EabKeyId:  account.EabKeyId,
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	unlock, err := b.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	accountPath, err := b.getAccountPath(account.Directory, account.Email)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(accountPath), dirPermissions); err != nil {
+		return fmt.Errorf("could not create account directory: %w", err)
+	}
+
+	return writeFileAtomic(accountPath, data, secretPermissions)
+}
+
+func (b *FilesystemBackend) ReadAccount(directory, email string) (*certstorage.AcmeAccount, error) {
+	unlock, err := b.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	accountPath, err := b.getAccountPath(directory, email)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := readFile(accountPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read account: %w", err)
+	}
+
+	var data accountData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("could not parse account: %w", err)
+	}
+
+	key, err := certstorage.FromPem([]byte(data.Key))
+	if err != nil {
+		return nil, fmt.Errorf("can not decode pem private key: %w", err)
+	}
+
+	return &certstorage.AcmeAccount{
+		Email: data.Email,
+		Key:   key,
+		Registration: &registration.Resource{
+			URI:  data.Uri,
+			Body: data.Account,
+		},
+		Directory: data.Directory,
+		EabKeyId:  data.EabKeyId,
+	}, nil
+}
+
+// ReadSecret reads the secret stored as JSON object at secrets/<path>.json.
+func (b *FilesystemBackend) ReadSecret(path string) (map[string]interface{}, error) {
+	secretPath, err := b.getPath("secrets", path+".json")
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := readFile(secretPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read secret: %w", err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("could not parse secret %s: %w", path, err)
+	}
+
+	return data, nil
+}
+
+// ReadAwsCredentials reads static AWS credentials from aws/credentials.json.
+func (b *FilesystemBackend) ReadAwsCredentials() (aws.Credentials, error) {
+	return b.ReadAwsCredentialsForRole("", defaultAwsRole)
+}
+
+// ReadAwsCredentialsForRole reads static AWS credentials from aws/<role>.json, the mount path is ignored. The
+// credentials do not expire, so they are read once and cached for the lifetime of the process.
+func (b *FilesystemBackend) ReadAwsCredentialsForRole(_, role string) (aws.Credentials, error) {
+	credentialsPath, err := b.getPath("aws", role+".json")
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	raw, err := readFile(credentialsPath)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("could not read aws credentials: %w", err)
+	}
+
+	var creds awsCredentials
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return aws.Credentials{}, fmt.Errorf("could not parse aws credentials: %w", err)
+	}
+
+	if len(creds.AccessKey) == 0 || len(creds.SecretKey) == 0 {
+		return aws.Credentials{}, errors.New("empty 'access_key' or 'secret_key'")
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     creds.AccessKey,
+		SecretAccessKey: creds.SecretKey,
+		SessionToken:    creds.SessionToken,
+		Source:          "filesystem",
+	}, nil
+}
+
+// lock acquires a lock on the directory that is shared with other processes using the same directory. Readers
+// acquire a shared lock, writers an exclusive lock.
+func (b *FilesystemBackend) lock(exclusive bool) (func(), error) {
+	file, err := os.OpenFile(filepath.Join(b.dir, lockFile), os.O_CREATE|os.O_RDONLY, secretPermissions)
+	if err != nil {
+		return nil, fmt.Errorf("could not open lock file: %w", err)
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+
+	if err := syscall.Flock(int(file.Fd()), how); err != nil { // #nosec G115
+		_ = file.Close()
+		return nil, fmt.Errorf("could not acquire lock: %w", err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN) // #nosec G115
+		_ = file.Close()
+	}, nil
+}
+
+func (b *FilesystemBackend) getCertDir(domain string) (string, error) {
+	return b.getPath("certs", domain)
+}
+
+func (b *FilesystemBackend) getAccountPath(directory, email string) (string, error) {
+	return b.getPath("accounts", config.AcmeDirectoryHost(directory), email+".json")
+}
+
+// getPath joins the given elements below the given subdirectory and makes sure the result does not escape it.
+func (b *FilesystemBackend) getPath(subdir string, elem ...string) (string, error) {
+	path := filepath.Join(elem...)
+	if !filepath.IsLocal(path) {
+		return "", fmt.Errorf("invalid path %q", path)
+	}
+
+	return filepath.Join(b.dir, subdir, path), nil
+}
+
+// readFile reads the file, translating a missing file to certstorage.ErrNotFound.
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path) // #nosec G304
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, certstorage.ErrNotFound
+		}
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, certstorage.ErrPermissionDenied
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// writeFileAtomic writes the data to a temporary file first and renames it afterwards, so readers never see
+// partially written files.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
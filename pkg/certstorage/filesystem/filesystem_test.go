@@ -0,0 +1,211 @@
+package filesystem
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+func newBackend(t *testing.T) *FilesystemBackend {
+	t.Helper()
+	backend, err := NewFilesystemBackend(config.FilesystemConfig{Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewFilesystemBackend() error = %v", err)
+	}
+	return backend
+}
+
+func TestFilesystemBackend_Certificate(t *testing.T) {
+	backend := newBackend(t)
+
+	cert := &certstorage.AcmeCertificate{
+		Domain:            "example.com",
+		CertURL:           "https://acme/cert/1",
+		CertStableURL:     "https://acme/cert/1/stable",
+		PrivateKey:        []byte("private key"),
+		Certificate:       []byte("certificate"),
+		IssuerCertificate: []byte("issuer"),
+		KeyRenewals:       2,
+	}
+	if err := backend.WriteCertificate(cert); err != nil {
+		t.Fatalf("WriteCertificate() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(backend.dir, "certs", "example.com", keyFile))
+	if err != nil {
+		t.Fatalf("could not stat private key: %v", err)
+	}
+	if info.Mode().Perm() != secretPermissions {
+		t.Errorf("private key permissions = %v, want %v", info.Mode().Perm(), os.FileMode(secretPermissions))
+	}
+
+	full, err := backend.ReadFullCertificateData("example.com")
+	if err != nil {
+		t.Fatalf("ReadFullCertificateData() error = %v", err)
+	}
+	if !reflect.DeepEqual(full, cert) {
+		t.Errorf("ReadFullCertificateData() got = %v, want %v", full, cert)
+	}
+
+	public, err := backend.ReadPublicCertificateData("example.com")
+	if err != nil {
+		t.Fatalf("ReadPublicCertificateData() error = %v", err)
+	}
+	if public.PrivateKey != nil {
+		t.Errorf("ReadPublicCertificateData() returned private key")
+	}
+	if !reflect.DeepEqual(public.Certificate, cert.Certificate) {
+		t.Errorf("ReadPublicCertificateData() got = %v, want %v", public.Certificate, cert.Certificate)
+	}
+
+	// renewing without a new private key keeps the existing key
+	renewed := *cert
+	renewed.PrivateKey = nil
+	renewed.Certificate = []byte("renewed certificate")
+	if err := backend.WriteCertificate(&renewed); err != nil {
+		t.Fatalf("WriteCertificate() error = %v", err)
+	}
+	full, err = backend.ReadFullCertificateData("example.com")
+	if err != nil {
+		t.Fatalf("ReadFullCertificateData() error = %v", err)
+	}
+	if string(full.PrivateKey) != "private key" || string(full.Certificate) != "renewed certificate" {
+		t.Errorf("ReadFullCertificateData() got = %v", full)
+	}
+}
+
+func TestFilesystemBackend_ReadCertificate_notFound(t *testing.T) {
+	backend := newBackend(t)
+
+	_, err := backend.ReadPublicCertificateData("example.com")
+	if !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadPublicCertificateData() error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+
+	_, err = backend.ReadFullCertificateData("example.com")
+	if !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadFullCertificateData() error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+}
+
+func TestFilesystemBackend_invalidPaths(t *testing.T) {
+	backend := newBackend(t)
+
+	if err := backend.WriteCertificate(&certstorage.AcmeCertificate{Domain: "../example.com"}); err == nil {
+		t.Errorf("WriteCertificate() expected error")
+	}
+	if _, err := backend.ReadPublicCertificateData("../../etc"); err == nil {
+		t.Errorf("ReadPublicCertificateData() expected error")
+	}
+	if _, err := backend.ReadSecret("../secret"); err == nil {
+		t.Errorf("ReadSecret() expected error")
+	}
+	if _, err := backend.ReadAwsCredentialsForRole("", "/etc/passwd"); err == nil {
+		t.Errorf("ReadAwsCredentialsForRole() expected error")
+	}
+}
+
+func TestFilesystemBackend_Account(t *testing.T) {
+	backend := newBackend(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := certstorage.AcmeAccount{
+		Email: "acme@example.com",
+		Key:   key,
+		Registration: &registration.Resource{
+			URI:  "https://acme/acct/1",
+			Body: acme.Account{Status: "valid", Contact: []string{"mailto:acme@example.com"}},
+		},
+		Directory: "https://acme-v02.api.letsencrypt.org/directory",
+	}
+
+	if _, err := backend.ReadAccount(account.Directory, account.Email); !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadAccount() error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+
+	if err := backend.WriteAccount(account); err != nil {
+		t.Fatalf("WriteAccount() error = %v", err)
+	}
+
+	got, err := backend.ReadAccount(account.Directory, account.Email)
+	if err != nil {
+		t.Fatalf("ReadAccount() error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, account) {
+		t.Errorf("ReadAccount() got = %v, want %v", *got, account)
+	}
+
+	if _, err := backend.ReadAccount("https://acme-staging-v02.api.letsencrypt.org/directory", account.Email); !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadAccount() for other directory error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+}
+
+func TestFilesystemBackend_ReadSecret(t *testing.T) {
+	backend := newBackend(t)
+	writeTestFile(t, backend, "secrets/dns/hetzner.json", `{"api_key": "secret"}`)
+
+	got, err := backend.ReadSecret("dns/hetzner")
+	if err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	want := map[string]interface{}{"api_key": "secret"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadSecret() got = %v, want %v", got, want)
+	}
+
+	if _, err := backend.ReadSecret("dns/other"); !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadSecret() error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+}
+
+func TestFilesystemBackend_ReadAwsCredentials(t *testing.T) {
+	backend := newBackend(t)
+	writeTestFile(t, backend, "aws/credentials.json", `{"access_key": "AKIA", "secret_key": "secret"}`)
+	writeTestFile(t, backend, "aws/route53.json", `{"access_key": "ASIA", "secret_key": "secret", "session_token": "token"}`)
+	writeTestFile(t, backend, "aws/invalid.json", `{"access_key": "AKIA"}`)
+
+	creds, err := backend.ReadAwsCredentials()
+	if err != nil {
+		t.Fatalf("ReadAwsCredentials() error = %v", err)
+	}
+	if creds.AccessKeyID != "AKIA" || creds.SecretAccessKey != "secret" || creds.CanExpire {
+		t.Errorf("ReadAwsCredentials() got = %v", creds)
+	}
+
+	creds, err = backend.ReadAwsCredentialsForRole("aws", "route53")
+	if err != nil {
+		t.Fatalf("ReadAwsCredentialsForRole() error = %v", err)
+	}
+	if creds.AccessKeyID != "ASIA" || creds.SessionToken != "token" {
+		t.Errorf("ReadAwsCredentialsForRole() got = %v", creds)
+	}
+
+	if _, err := backend.ReadAwsCredentialsForRole("aws", "invalid"); err == nil {
+		t.Errorf("ReadAwsCredentialsForRole() expected error for incomplete credentials")
+	}
+}
+
+func writeTestFile(t *testing.T, backend *FilesystemBackend, name, content string) {
+	t.Helper()
+	path := filepath.Join(backend.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
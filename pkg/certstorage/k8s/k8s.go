@@ -0,0 +1,436 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	timeout = 10 * time.Second
+
+	defaultSecretNameFormat     = "%s-tls"
+	defaultAccountSecretName    = "acmevault-account"
+	defaultAwsCredentialsSecret = "acmevault-aws"
+
+	// awsCredentialsLifetime defines how long credentials read from a secret are used before reading them again.
+	awsCredentialsLifetime = time.Hour
+
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "acmevault"
+
+	annotationDomain      = "acmevault/domain"
+	annotationUrl         = "acmevault/url"
+	annotationStableUrl   = "acmevault/stable-url"
+	annotationKeyRenewals = "acmevault/key-renewals"
+
+	accountKeyEmail     = "email"
+	accountKeyUri       = "uri"
+	accountKeyAccount   = "account"
+	accountKeyKey       = "key"
+	accountKeyDirectory = "directory"
+	accountKeyEabKeyId  = "eab_kid"
+
+	awsKeyAccessKey    = "access_key"
+	awsKeySecretKey    = "secret_key"
+	awsKeySessionToken = "session_token"
+)
+
+// KubernetesBackend stores each certificate as a secret of type kubernetes.io/tls, so workloads running in the
+// cluster can consume it natively. The ACME account is stored in a secret of its own.
+type KubernetesBackend struct {
+	client            kubernetes.Interface
+	namespace         string
+	secretNameFormat  string
+	accountSecretName string
+}
+
+func NewKubernetesBackend(client kubernetes.Interface, conf config.KubernetesConfig) (*KubernetesBackend, error) {
+	if client == nil {
+		return nil, errors.New("no kubernetes client given")
+	}
+
+	if len(conf.Namespace) == 0 {
+		return nil, errors.New("no namespace given")
+	}
+
+	backend := &KubernetesBackend{
+		client:            client,
+		namespace:         conf.Namespace,
+		secretNameFormat:  defaultSecretNameFormat,
+		accountSecretName: defaultAccountSecretName,
+	}
+
+	if len(conf.SecretNameFormat) > 0 {
+		backend.secretNameFormat = conf.SecretNameFormat
+	}
+
+	if len(conf.AccountSecretName) > 0 {
+		backend.accountSecretName = conf.AccountSecretName
+	}
+
+	return backend, nil
+}
+
+func (b *KubernetesBackend) Authenticate() error {
+	return nil
+}
+
+func (b *KubernetesBackend) Logout() error {
+	return nil
+}
+
+func (b *KubernetesBackend) WriteCertificate(resource *certstorage.AcmeCertificate) error {
+	if resource == nil {
+		return errors.New("empty certificate provided")
+	}
+
+	name, err := b.getCertSecretName(resource.Domain)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	secret, err := b.getManagedSecret(ctx, name)
+	if err != nil && !errors.Is(err, certstorage.ErrNotFound) {
+		return fmt.Errorf("could not read secret for domain %s: %w", resource.Domain, err)
+	}
+
+	exists := secret != nil
+	if !exists {
+		secret = b.newSecret(name, corev1.SecretTypeTLS)
+	}
+
+	annotations := map[string]string{
+		annotationDomain:    resource.Domain,
+		annotationUrl:       resource.CertURL,
+		annotationStableUrl: resource.CertStableURL,
+	}
+	if resource.KeyRenewals > 0 {
+		annotations[annotationKeyRenewals] = strconv.Itoa(resource.KeyRenewals)
+	} else {
+		delete(secret.Annotations, annotationKeyRenewals)
+	}
+	for key, val := range annotations {
+		secret.Annotations[key] = val
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[corev1.TLSCertKey] = resource.Certificate
+	secret.Data[corev1.ServiceAccountRootCAKey] = resource.IssuerCertificate
+	// keep the existing key if no new key is provided
+	if resource.PrivateKey != nil {
+		secret.Data[corev1.TLSPrivateKeyKey] = resource.PrivateKey
+	}
+
+	if err := b.writeSecret(ctx, secret, exists); err != nil {
+		return fmt.Errorf("could not write secret for domain %s: %w", resource.Domain, err)
+	}
+
+	return nil
+}
+
+func (b *KubernetesBackend) ReadPublicCertificateData(domain string) (*certstorage.AcmeCertificate, error) {
+	secret, err := b.readCertSecret(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	return secretToCert(secret)
+}
+
+func (b *KubernetesBackend) ReadFullCertificateData(domain string) (*certstorage.AcmeCertificate, error) {
+	secret, err := b.readCertSecret(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := secretToCert(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := secret.Data[corev1.TLSPrivateKeyKey]
+	if !ok || len(privateKey) == 0 {
+		return nil, fmt.Errorf("no private key data available for domain %s", domain)
+	}
+	cert.PrivateKey = privateKey
+
+	return cert, nil
+}
+
+func (b *KubernetesBackend) readCertSecret(domain string) (*corev1.Secret, error) {
+	name, err := b.getCertSecretName(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	secret, err := b.getManagedSecret(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read secret for domain %s: %w", domain, err)
+	}
+
+	return secret, nil
+}
+
+func (b *KubernetesBackend) WriteAccount(account certstorage.AcmeAccount) error {
+	if account.Registration == nil {
+		return errors.New("account is not registered")
+	}
+
+	accountJson, err := json.Marshal(account.Registration.Body)
+	if err != nil {
+		return err
+	}
+
+	key, err := certstorage.ConvertToPem(account.Key)
+	if err != nil {
+		return fmt.Errorf("could not encode account key: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	secret, err := b.getManagedSecret(ctx, b.accountSecretName)
+	if err != nil && !errors.Is(err, certstorage.ErrNotFound) {
+		return fmt.Errorf("could not read account secret: %w", err)
+	}
+
+	exists := secret != nil
+	if !exists {
+		secret = b.newSecret(b.accountSecretName, corev1.SecretTypeOpaque)
+	}
+
+	secret.Data = map[string][]byte{
+		accountKeyEmail:     []byte(account.Email),
+		accountKeyUri:       []byte(account.Registration.URI),
+		accountKeyAccount:   accountJson,
+		accountKeyKey:       []byte(key),
+		accountKeyDirectory: []byte(account.Directory),
+	}
+	if len(account.EabKeyId) > 0 {
+		secret.Data[accountKeyEabKeyId] = []byte(account.EabKeyId)
+	}
+
+	if err := b.writeSecret(ctx, secret, exists); err != nil {
+		return fmt.Errorf("could not write account secret: %w", err)
+	}
+
+	return nil
+}
+
+// ReadAccount reads the account from its secret. As only a single account is stored, an account that has been
+// registered for a different directory or email is treated as missing.
+func (b *KubernetesBackend) ReadAccount(directory, email string) (*certstorage.AcmeAccount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	secret, err := b.getManagedSecret(ctx, b.accountSecretName)
+	if err != nil {
+		return nil, fmt.Errorf("could not read account secret: %w", err)
+	}
+
+	if string(secret.Data[accountKeyEmail]) != email ||
+		config.AcmeDirectoryHost(string(secret.Data[accountKeyDirectory])) != config.AcmeDirectoryHost(directory) {
+		return nil, fmt.Errorf("no account for %s at %s: %w", email, directory, certstorage.ErrNotFound)
+	}
+
+	var body acme.Account
+	if err := json.Unmarshal(secret.Data[accountKeyAccount], &body); err != nil {
+		return nil, fmt.Errorf("could not parse account: %w", err)
+	}
+
+	key, err := certstorage.FromPem(secret.Data[accountKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("can not decode pem private key: %w", err)
+	}
+
+	return &certstorage.AcmeAccount{
+		Email: email,
+		Key:   key,
+		Registration: &registration.Resource{
+			URI:  string(secret.Data[accountKeyUri]),
+			Body: body,
+		},
+		Directory: directory,
+		EabKeyId:  string(secret.Data[accountKeyEabKeyId]),
+	}, nil
+}
+
+// ReadSecret reads the data of the secret with the given path, slashes in the path are replaced by dashes to form
+// the name of the secret.
+func (b *KubernetesBackend) ReadSecret(path string) (map[string]interface{}, error) {
+	secret, err := b.readSecret(strings.ReplaceAll(path, "/", "-"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read secret %s: %w", path, err)
+	}
+
+	data := make(map[string]interface{}, len(secret.Data))
+	for key, val := range secret.Data {
+		data[key] = string(val)
+	}
+
+	return data, nil
+}
+
+// ReadAwsCredentials reads static AWS credentials from the secret 'acmevault-aws'.
+func (b *KubernetesBackend) ReadAwsCredentials() (aws.Credentials, error) {
+	return b.ReadAwsCredentialsForRole("", defaultAwsCredentialsSecret)
+}
+
+// ReadAwsCredentialsForRole reads static AWS credentials from the secret named after the role, the mount path is
+// ignored.
+func (b *KubernetesBackend) ReadAwsCredentialsForRole(_, role string) (aws.Credentials, error) {
+	secret, err := b.readSecret(role)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("could not read aws credentials: %w", err)
+	}
+
+	accessKey := string(secret.Data[awsKeyAccessKey])
+	secretKey := string(secret.Data[awsKeySecretKey])
+	if len(accessKey) == 0 || len(secretKey) == 0 {
+		return aws.Credentials{}, errors.New("empty 'access_key' or 'secret_key'")
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SessionToken:    string(secret.Data[awsKeySessionToken]),
+		CanExpire:       true,
+		Expires:         time.Now().Add(awsCredentialsLifetime),
+		Source:          "kubernetes",
+	}, nil
+}
+
+func (b *KubernetesBackend) readSecret(name string) (*corev1.Secret, error) {
+	if err := validateSecretName(name); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	secret, err := b.client.CoreV1().Secrets(b.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return secret, nil
+}
+
+// getManagedSecret reads a secret that has been written by acmevault and refuses to return secrets that are
+// managed by someone else, so they are never overwritten.
+func (b *KubernetesBackend) getManagedSecret(ctx context.Context, name string) (*corev1.Secret, error) {
+	secret, err := b.client.CoreV1().Secrets(b.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if secret.Labels[managedByLabel] != managedByValue {
+		return nil, fmt.Errorf("secret %s/%s is not managed by acmevault", b.namespace, name)
+	}
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+
+	return secret, nil
+}
+
+func (b *KubernetesBackend) writeSecret(ctx context.Context, secret *corev1.Secret, exists bool) error {
+	var err error
+	if !exists {
+		_, err = b.client.CoreV1().Secrets(b.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	} else {
+		_, err = b.client.CoreV1().Secrets(b.namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	}
+
+	return translateError(err)
+}
+
+func (b *KubernetesBackend) newSecret(name string, secretType corev1.SecretType) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: b.namespace,
+			Labels: map[string]string{
+				managedByLabel: managedByValue,
+			},
+			Annotations: map[string]string{},
+		},
+		Type: secretType,
+	}
+}
+
+func (b *KubernetesBackend) getCertSecretName(domain string) (string, error) {
+	domain = strings.ToLower(strings.ReplaceAll(domain, "*", "wildcard"))
+	name := fmt.Sprintf(b.secretNameFormat, domain)
+	if err := validateSecretName(name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+func secretToCert(secret *corev1.Secret) (*certstorage.AcmeCertificate, error) {
+	cert := &certstorage.AcmeCertificate{
+		Domain:            secret.Annotations[annotationDomain],
+		CertURL:           secret.Annotations[annotationUrl],
+		CertStableURL:     secret.Annotations[annotationStableUrl],
+		Certificate:       secret.Data[corev1.TLSCertKey],
+		IssuerCertificate: secret.Data[corev1.ServiceAccountRootCAKey],
+	}
+
+	if renewals, ok := secret.Annotations[annotationKeyRenewals]; ok {
+		var err error
+		cert.KeyRenewals, err = strconv.Atoi(renewals)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse annotation %s: %w", annotationKeyRenewals, err)
+		}
+	}
+
+	return cert, nil
+}
+
+func validateSecretName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid secret name %q: %s", name, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsNotFound(err):
+		return certstorage.ErrNotFound
+	case apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err):
+		return certstorage.ErrPermissionDenied
+	default:
+		return err
+	}
+}
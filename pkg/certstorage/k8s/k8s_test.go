@@ -0,0 +1,272 @@
+package k8s
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-acme/lego/v4/acme"
+	"github.com/go-acme/lego/v4/registration"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const namespace = "acmevault"
+
+func newBackend(t *testing.T, objects ...*corev1.Secret) (*KubernetesBackend, *fake.Clientset) {
+	t.Helper()
+	client := fake.NewSimpleClientset()
+	for _, obj := range objects {
+		if _, err := client.CoreV1().Secrets(obj.Namespace).Create(context.Background(), obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backend, err := NewKubernetesBackend(client, config.KubernetesConfig{Namespace: namespace})
+	if err != nil {
+		t.Fatalf("NewKubernetesBackend() error = %v", err)
+	}
+	return backend, client
+}
+
+func TestKubernetesBackend_getCertSecretName(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		domain  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "default format",
+			domain: "example.com",
+			want:   "example.com-tls",
+		},
+		{
+			name:   "custom format",
+			format: "cert-%s",
+			domain: "Example.com",
+			want:   "cert-example.com",
+		},
+		{
+			name:   "wildcard",
+			domain: "*.example.com",
+			want:   "wildcard.example.com-tls",
+		},
+		{
+			name:    "invalid name",
+			domain:  "example_com",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := NewKubernetesBackend(fake.NewSimpleClientset(), config.KubernetesConfig{
+				Namespace:        namespace,
+				SecretNameFormat: tt.format,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := backend.getCertSecretName(tt.domain)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getCertSecretName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getCertSecretName() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubernetesBackend_Certificate(t *testing.T) {
+	backend, client := newBackend(t)
+
+	cert := &certstorage.AcmeCertificate{
+		Domain:            "example.com",
+		CertURL:           "https://acme/cert/1",
+		CertStableURL:     "https://acme/cert/1/stable",
+		PrivateKey:        []byte("private key"),
+		Certificate:       []byte("certificate"),
+		IssuerCertificate: []byte("issuer"),
+		KeyRenewals:       1,
+	}
+	if err := backend.WriteCertificate(cert); err != nil {
+		t.Fatalf("WriteCertificate() error = %v", err)
+	}
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.Background(), "example.com-tls", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("could not read secret: %v", err)
+	}
+	if secret.Type != corev1.SecretTypeTLS {
+		t.Errorf("secret type = %v, want %v", secret.Type, corev1.SecretTypeTLS)
+	}
+	wantData := map[string][]byte{
+		"tls.crt": []byte("certificate"),
+		"tls.key": []byte("private key"),
+		"ca.crt":  []byte("issuer"),
+	}
+	if !reflect.DeepEqual(secret.Data, wantData) {
+		t.Errorf("secret data = %v, want %v", secret.Data, wantData)
+	}
+
+	full, err := backend.ReadFullCertificateData("example.com")
+	if err != nil {
+		t.Fatalf("ReadFullCertificateData() error = %v", err)
+	}
+	if !reflect.DeepEqual(full, cert) {
+		t.Errorf("ReadFullCertificateData() got = %v, want %v", full, cert)
+	}
+
+	public, err := backend.ReadPublicCertificateData("example.com")
+	if err != nil {
+		t.Fatalf("ReadPublicCertificateData() error = %v", err)
+	}
+	if public.PrivateKey != nil {
+		t.Errorf("ReadPublicCertificateData() returned private key")
+	}
+
+	// updating keeps annotations added by others
+	secret.Annotations["reloader"] = "true"
+	if _, err := client.CoreV1().Secrets(namespace).Update(context.Background(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	renewed := *cert
+	renewed.Certificate = []byte("renewed certificate")
+	renewed.KeyRenewals = 0
+	if err := backend.WriteCertificate(&renewed); err != nil {
+		t.Fatalf("WriteCertificate() error = %v", err)
+	}
+
+	full, err = backend.ReadFullCertificateData("example.com")
+	if err != nil {
+		t.Fatalf("ReadFullCertificateData() error = %v", err)
+	}
+	if !reflect.DeepEqual(full, &renewed) {
+		t.Errorf("ReadFullCertificateData() got = %v, want %v", full, &renewed)
+	}
+
+	secret, err = client.CoreV1().Secrets(namespace).Get(context.Background(), "example.com-tls", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Annotations["reloader"] != "true" {
+		t.Errorf("foreign annotation has been removed")
+	}
+}
+
+func TestKubernetesBackend_ReadCertificate_notFound(t *testing.T) {
+	backend, _ := newBackend(t)
+
+	_, err := backend.ReadPublicCertificateData("example.com")
+	if !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadPublicCertificateData() error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+}
+
+func TestKubernetesBackend_WriteCertificate_unmanagedSecret(t *testing.T) {
+	backend, _ := newBackend(t, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example.com-tls",
+			Namespace: namespace,
+		},
+		Type: corev1.SecretTypeTLS,
+	})
+
+	err := backend.WriteCertificate(&certstorage.AcmeCertificate{
+		Domain:      "example.com",
+		Certificate: []byte("certificate"),
+		PrivateKey:  []byte("private key"),
+	})
+	if err == nil {
+		t.Errorf("WriteCertificate() expected error when overwriting unmanaged secret")
+	}
+}
+
+func TestKubernetesBackend_Account(t *testing.T) {
+	backend, _ := newBackend(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := certstorage.AcmeAccount{
+		Email: "acme@example.com",
+		Key:   key,
+		Registration: &registration.Resource{
+			URI:  "https://acme/acct/1",
+			Body: acme.Account{Status: "valid", Contact: []string{"mailto:acme@example.com"}},
+		},
+		Directory: "https://acme-v02.api.letsencrypt.org/directory",
+		EabKeyId:  "kid",
+	}
+
+	if _, err := backend.ReadAccount(account.Directory, account.Email); !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadAccount() error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+
+	if err := backend.WriteAccount(account); err != nil {
+		t.Fatalf("WriteAccount() error = %v", err)
+	}
+
+	got, err := backend.ReadAccount(account.Directory, account.Email)
+	if err != nil {
+		t.Fatalf("ReadAccount() error = %v", err)
+	}
+	if !reflect.DeepEqual(*got, account) {
+		t.Errorf("ReadAccount() got = %v, want %v", *got, account)
+	}
+
+	if _, err := backend.ReadAccount("https://acme-staging-v02.api.letsencrypt.org/directory", account.Email); !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadAccount() for other directory error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+}
+
+func TestKubernetesBackend_ReadSecret(t *testing.T) {
+	backend, _ := newBackend(t,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "acmevault-dns-hetzner", Namespace: namespace},
+			Data:       map[string][]byte{"api_key": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "route53", Namespace: namespace},
+			Data: map[string][]byte{
+				"access_key":    []byte("ASIA"),
+				"secret_key":    []byte("secret"),
+				"session_token": []byte("token"),
+			},
+		},
+	)
+
+	got, err := backend.ReadSecret("acmevault/dns/hetzner")
+	if err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	want := map[string]interface{}{"api_key": "secret"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadSecret() got = %v, want %v", got, want)
+	}
+
+	creds, err := backend.ReadAwsCredentialsForRole("aws", "route53")
+	if err != nil {
+		t.Fatalf("ReadAwsCredentialsForRole() error = %v", err)
+	}
+	if creds.AccessKeyID != "ASIA" || creds.SecretAccessKey != "secret" || creds.SessionToken != "token" {
+		t.Errorf("ReadAwsCredentialsForRole() got = %v", creds)
+	}
+
+	if _, err := backend.ReadAwsCredentials(); !errors.Is(err, certstorage.ErrNotFound) {
+		t.Errorf("ReadAwsCredentials() error = %v, want %v", err, certstorage.ErrNotFound)
+	}
+}
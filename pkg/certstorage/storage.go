@@ -2,28 +2,24 @@ package certstorage
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
 	"time"
 
 	"github.com/go-acme/lego/v4/registration"
 	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/config"
 	"github.com/soerenschneider/acmevault/internal/metrics"
 )
 
-const (
-	// MinCertLifetime defines a certs minimum validity. If a certificate's lifetime is less than this threshold, it's
-	// being renewed.
-	MinCertLifetime = time.Duration(24) * time.Hour * 30
-	Skew            = time.Duration(24) * time.Hour * 45
-)
-
 var ErrNotFound = errors.New("not found")
 var ErrPermissionDenied = errors.New("permission denied")
-var rnd *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano())) // #nosec G404
 
 var ErrAccountNotFound = errors.New("account not found")
 
@@ -35,6 +31,8 @@ type AcmeCertificate struct {
 	Certificate       []byte `json:"-"`
 	IssuerCertificate []byte `json:"-"`
 	CSR               []byte `json:"-"`
+	// KeyRenewals is the number of renewals the private key has been reused for.
+	KeyRenewals int `json:"keyRenewals"`
 }
 
 func niceTimeLeft(duration time.Duration) string {
@@ -49,42 +47,87 @@ func niceTimeLeft(duration time.Duration) string {
 	return fmt.Sprintf("%d days %d hours", days, hours)
 }
 
-func (cert *AcmeCertificate) NeedsRenewal() (bool, error) {
-	expiry, err := cert.GetExpiryTimestamp()
+// NeedsRenewal returns whether the cert should be renewed according to the given renewal config.
+func (cert *AcmeCertificate) NeedsRenewal(policy config.RenewalConfig) (bool, error) {
+	parsed, err := cert.parseCertificate()
 	if err != nil {
 		return false, fmt.Errorf("could not determine cert expiry for domain '%s': %v", cert.Domain, err)
 	}
 
-	metrics.CertServerExpiryTimestamp.WithLabelValues(cert.Domain).Set(float64(expiry.Unix()))
-	timeLeft := expiry.Sub(time.Now().UTC())
-	log.Debug().Str("domain", cert.Domain).Msgf("Not renewing cert still valid for %s", niceTimeLeft(timeLeft))
+	metrics.CertServerExpiryTimestamp.WithLabelValues(cert.Domain).Set(float64(parsed.NotAfter.Unix()))
+	now := time.Now().UTC()
+	timeLeft := parsed.NotAfter.Sub(now)
 
-	if timeLeft > MinCertLifetime && timeLeft <= Skew {
-		if rnd.Intn(100) >= 97 {
-			log.Info().Str("domain", cert.Domain).Msgf("Earlier renewal of cert, time left (%s)", niceTimeLeft(timeLeft))
-			return true, nil
-		}
+	hash := fnv.New64a()
+	_, _ = hash.Write(parsed.Raw)
+	renewAt := policy.RenewalTime(parsed.NotBefore, parsed.NotAfter, hash.Sum64())
+	if now.Before(renewAt) {
+		log.Debug().Str("domain", cert.Domain).Msgf("Not renewing cert still valid for %s", niceTimeLeft(timeLeft))
+		return false, nil
 	}
 
-	return timeLeft <= MinCertLifetime, nil
+	log.Info().Str("domain", cert.Domain).Msgf("Renewal of cert due, time left (%s)", niceTimeLeft(timeLeft))
+	return true, nil
 }
 
-func (cert *AcmeCertificate) GetExpiryTimestamp() (time.Time, error) {
+func (cert *AcmeCertificate) parseCertificate() (*x509.Certificate, error) {
 	block, _ := pem.Decode(cert.Certificate)
 	if block == nil {
-		return time.Time{}, errors.New("could not parse pem block from cert")
+		return nil, errors.New("could not parse pem block from cert")
 	}
 
 	parsed, err := x509.ParseCertificates(block.Bytes)
 	if err != nil {
-		return time.Time{}, fmt.Errorf("could not parse certificate: %v", err)
+		return nil, fmt.Errorf("could not parse certificate: %v", err)
 	}
 
 	if len(parsed) == 0 {
-		return time.Time{}, errors.New("no (valid) certificate data found")
+		return nil, errors.New("no (valid) certificate data found")
+	}
+
+	return parsed[0], nil
+}
+
+func (cert *AcmeCertificate) GetExpiryTimestamp() (time.Time, error) {
+	parsed, err := cert.parseCertificate()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return parsed.NotAfter, nil
+}
+
+// GetDnsNames returns the DNS names the certificate has been issued for.
+func (cert *AcmeCertificate) GetDnsNames() ([]string, error) {
+	parsed, err := cert.parseCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.DNSNames, nil
+}
+
+// GetKeyType returns the type of the certificate's public key using the names of the key types in the config.
+func (cert *AcmeCertificate) GetKeyType() (string, error) {
+	parsed, err := cert.parseCertificate()
+	if err != nil {
+		return "", err
+	}
+
+	switch key := parsed.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("rsa%d", key.N.BitLen()), nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return config.KeyTypeEc256, nil
+		case elliptic.P384():
+			return config.KeyTypeEc384, nil
+		}
+		return "", fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
 	}
 
-	return parsed[0].NotAfter, nil
+	return "", fmt.Errorf("unsupported public key type %T", parsed.PublicKey)
 }
 
 func (cert *AcmeCertificate) GetDurationUntilExpiry() (time.Duration, error) {
@@ -101,6 +144,10 @@ type AcmeAccount struct {
 	Email        string
 	Key          crypto.PrivateKey
 	Registration *registration.Resource
+	// Directory is the ACME directory URL of the CA this account is registered at.
+	Directory string
+	// EabKeyId is the key id of the external account binding used to register the account, if any.
+	EabKeyId string
 }
 
 func (account AcmeAccount) IsInitialized() bool {
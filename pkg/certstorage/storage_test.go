@@ -0,0 +1,152 @@
+package certstorage
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+func selfSignedCert(t *testing.T, key crypto.Signer) []byte {
+	t.Helper()
+	return selfSignedCertWithValidity(t, key, time.Now(), time.Now().Add(time.Hour))
+}
+
+func selfSignedCertWithValidity(t *testing.T, key crypto.Signer, notBefore, notAfter time.Time) []byte {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestAcmeCertificate_GetKeyType(t *testing.T) {
+	ec256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ec384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	ec521, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	rsa2048, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name    string
+		cert    []byte
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ec256",
+			cert: selfSignedCert(t, ec256),
+			want: config.KeyTypeEc256,
+		},
+		{
+			name: "ec384",
+			cert: selfSignedCert(t, ec384),
+			want: config.KeyTypeEc384,
+		},
+		{
+			name:    "unsupported curve",
+			cert:    selfSignedCert(t, ec521),
+			wantErr: true,
+		},
+		{
+			name: "rsa2048",
+			cert: selfSignedCert(t, rsa2048),
+			want: config.KeyTypeRsa2048,
+		},
+		{
+			name:    "no certificate",
+			cert:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := &AcmeCertificate{Certificate: tt.cert}
+			got, err := cert.GetKeyType()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetKeyType() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetKeyType() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcmeCertificate_NeedsRenewal(t *testing.T) {
+	day := 24 * time.Hour
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		policy    config.RenewalConfig
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "fresh 90 day cert",
+			notBefore: now.Add(-day),
+			notAfter:  now.Add(89 * day),
+			want:      false,
+		},
+		{
+			name:      "90 day cert after two thirds",
+			notBefore: now.Add(-61 * day),
+			notAfter:  now.Add(29 * day),
+			want:      true,
+		},
+		{
+			name:      "6 day cert not renewed on every check",
+			notBefore: now.Add(-day),
+			notAfter:  now.Add(5 * day),
+			want:      false,
+		},
+		{
+			name:      "fixed duration",
+			notBefore: now.Add(-60 * day),
+			notAfter:  now.Add(30 * day),
+			policy:    config.RenewalConfig{Before: 14 * day},
+			want:      false,
+		},
+		{
+			name:      "fixed duration due",
+			notBefore: now.Add(-80 * day),
+			notAfter:  now.Add(10 * day),
+			policy:    config.RenewalConfig{Before: 14 * day},
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := &AcmeCertificate{Certificate: selfSignedCertWithValidity(t, key, tt.notBefore, tt.notAfter)}
+			got, err := cert.NeedsRenewal(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NeedsRenewal() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("NeedsRenewal() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
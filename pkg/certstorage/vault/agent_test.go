@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+func TestVaultBackend_agentSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mutex sync.Mutex
+	var paths []string
+	var tokens []string
+	kv := &fakeKv{version: kvVersion2, data: map[string]map[string]interface{}{}}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.Path)
+		tokens = append(tokens, r.Header.Get("X-Vault-Token"))
+		mutex.Unlock()
+		kv.ServeHTTP(w, r)
+	})} // #nosec G112
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	t.Setenv("VAULT_TOKEN", "")
+	vaultConf := config.VaultConfig{
+		AuthMethod:   "agent",
+		AgentAddr:    "unix://" + socket,
+		PathPrefix:   "prod",
+		Kv2MountPath: "secret",
+		KvVersion:    config.KvVersion2,
+	}
+
+	clientConf := api.DefaultConfig()
+	clientConf.Address = vaultConf.GetAddr()
+	client, err := api.NewClient(clientConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend, err := NewVaultBackend(client, vaultConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = backend.WriteCertificate(&certstorage.AcmeCertificate{
+		Domain:            "example.com",
+		PrivateKey:        []byte("private key"),
+		Certificate:       []byte("certificate"),
+		IssuerCertificate: []byte("issuer"),
+	})
+	if err != nil {
+		t.Fatalf("WriteCertificate() error = %v", err)
+	}
+
+	if _, err := backend.ReadFullCertificateData("example.com"); err != nil {
+		t.Fatalf("ReadFullCertificateData() error = %v", err)
+	}
+
+	requests := len(paths)
+	if err := backend.Logout(); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if len(paths) != requests {
+		t.Errorf("Logout() sent request to the agent: %v", paths[requests:])
+	}
+
+	for _, token := range tokens {
+		if len(token) > 0 {
+			t.Errorf("request carried token %q, the agent's token must be used", token)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/api/auth/approle"
+)
+
+const approleMountPath = "approle"
+
+// invalidSecretIdErrors are the errors Vault responds with when logging in with a secret id that does not exist (anymore),
+// older versions of Vault use the first one.
+var invalidSecretIdErrors = []string{"invalid secret id", "invalid role or secret id"}
+
+var ErrSecretIdConsumed = errors.New("secret id unwrapped at startup is no longer valid, it has probably been consumed or expired; restart acmevault with a new wrapped secret id")
+
+// WrappedAppRoleAuth logs in using AppRole with a secret id that has been handed out as response-wrapped token. The
+// token is unwrapped once when building the auth method, as wrapping tokens can only be used a single time.
+type WrappedAppRoleAuth struct {
+	auth *approle.AppRoleAuth
+}
+
+func NewWrappedAppRoleAuth(ctx context.Context, client *api.Client, roleId, wrappingToken string) (*WrappedAppRoleAuth, error) {
+	if client == nil {
+		return nil, errors.New("empty client passed")
+	}
+
+	wrappingToken = strings.TrimSpace(wrappingToken)
+	if len(wrappingToken) == 0 {
+		return nil, errors.New("empty wrapping token passed")
+	}
+
+	secretId, err := unwrapSecretId(ctx, client, wrappingToken)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := approle.NewAppRoleAuth(roleId, &approle.SecretID{FromString: secretId})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WrappedAppRoleAuth{auth: auth}, nil
+}
+
+func (t *WrappedAppRoleAuth) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	secret, err := t.auth.Login(ctx, client)
+	if err != nil {
+		if isInvalidSecretIdError(err) {
+			return nil, fmt.Errorf("%w: %w", ErrSecretIdConsumed, err)
+		}
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// isInvalidSecretIdError returns whether Vault rejected the login because of an invalid secret id, other bad requests
+// such as an unknown role id are not caused by the secret id being consumed.
+func isInvalidSecretIdError(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+
+	for _, msg := range respErr.Errors {
+		msg = strings.ToLower(msg)
+		for _, invalid := range invalidSecretIdErrors {
+			if strings.Contains(msg, invalid) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unwrapSecretId validates that the wrapping token has been created for an AppRole secret id and unwraps it. A copy
+// of the client including its headers is used, as unwrapping authenticates with the wrapping token itself but has to
+// happen in the same namespace as the lookup.
+func unwrapSecretId(ctx context.Context, client *api.Client, wrappingToken string) (string, error) {
+	lookup, err := client.Logical().WriteWithContext(ctx, "sys/wrapping/lookup", map[string]interface{}{
+		"token": wrappingToken,
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not lookup wrapping token, it may have been used already or expired: %w", err)
+	}
+
+	if lookup == nil || lookup.Data == nil {
+		return "", errors.New("empty response when looking up wrapping token")
+	}
+
+	creationPath, _ := lookup.Data["creation_path"].(string)
+	if !isSecretIdCreationPath(creationPath) {
+		return "", fmt.Errorf("wrapping token has been created at unexpected path %q, refusing to unwrap it", creationPath)
+	}
+
+	unwrapClient, err := client.CloneWithHeaders()
+	if err != nil {
+		return "", err
+	}
+	unwrapClient.SetToken(wrappingToken)
+
+	secret, err := unwrapClient.Logical().UnwrapWithContext(ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("could not unwrap secret id: %w", err)
+	}
+
+	if secret == nil || secret.Data == nil {
+		return "", errors.New("empty response when unwrapping secret id")
+	}
+
+	secretId, ok := secret.Data["secret_id"].(string)
+	if !ok || len(secretId) == 0 {
+		return "", errors.New("no secret id in unwrapped response")
+	}
+
+	return secretId, nil
+}
+
+func isSecretIdCreationPath(path string) bool {
+	prefix := fmt.Sprintf("auth/%s/role/", approleMountPath)
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	role, suffix, found := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+	return found && len(role) > 0 && (suffix == "secret-id" || suffix == "custom-secret-id")
+}
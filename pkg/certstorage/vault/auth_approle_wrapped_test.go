@@ -0,0 +1,190 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// fakeWrapping emulates the wrapping and approle endpoints, the secret id can only be used for a single login.
+type fakeWrapping struct {
+	creationPath string
+	unwraps      int
+	logins       int
+	// loginError overrides the error returned for a rejected login
+	loginError string
+	// namespaces records the namespace header of each request by path
+	namespaces map[string]string
+}
+
+func (f *fakeWrapping) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	if f.namespaces != nil {
+		f.namespaces[r.URL.Path] = r.Header.Get("X-Vault-Namespace")
+	}
+
+	switch r.URL.Path {
+	case "/v1/sys/wrapping/lookup":
+		if body["token"] != "wrapping-token" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJson(w, map[string]interface{}{"errors": []string{"wrapping token is not valid or does not exist"}})
+			return
+		}
+		writeJson(w, map[string]interface{}{"data": map[string]interface{}{"creation_path": f.creationPath}})
+	case "/v1/sys/wrapping/unwrap":
+		if r.Header.Get("X-Vault-Token") != "wrapping-token" || f.unwraps > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJson(w, map[string]interface{}{"errors": []string{"wrapping token is not valid or does not exist"}})
+			return
+		}
+		f.unwraps++
+		writeJson(w, map[string]interface{}{"data": map[string]interface{}{"secret_id": "secret-id"}})
+	case "/v1/auth/approle/login":
+		if body["role_id"] != "role-id" || body["secret_id"] != "secret-id" || f.logins > 0 {
+			loginError := "invalid role or secret ID"
+			if len(f.loginError) > 0 {
+				loginError = f.loginError
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			writeJson(w, map[string]interface{}{"errors": []string{loginError}})
+			return
+		}
+		f.logins++
+		writeJson(w, map[string]interface{}{"auth": map[string]interface{}{"client_token": "token"}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeWrappingClient(t *testing.T, fake *fakeWrapping) *api.Client {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	conf := api.DefaultConfig()
+	conf.Address = server.URL
+	conf.MaxRetries = 0
+	client, err := api.NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ClearToken()
+	return client
+}
+
+func TestWrappedAppRoleAuth(t *testing.T) {
+	fake := &fakeWrapping{creationPath: "auth/approle/role/acmevault/secret-id"}
+	client := newFakeWrappingClient(t, fake)
+
+	auth, err := NewWrappedAppRoleAuth(context.Background(), client, "role-id", "wrapping-token\n")
+	if err != nil {
+		t.Fatalf("NewWrappedAppRoleAuth() error = %v", err)
+	}
+	if len(client.Token()) > 0 {
+		t.Errorf("unwrapping modified the token of the client")
+	}
+
+	if _, err := client.Auth().Login(context.Background(), auth); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if client.Token() != "token" {
+		t.Errorf("Login() did not set token on client")
+	}
+
+	// the secret id has been consumed by the first login
+	_, err = client.Auth().Login(context.Background(), auth)
+	if !errors.Is(err, ErrSecretIdConsumed) {
+		t.Errorf("Login() error = %v, want %v", err, ErrSecretIdConsumed)
+	}
+
+	// the wrapping token can not be used twice
+	if _, err := NewWrappedAppRoleAuth(context.Background(), client, "role-id", "wrapping-token"); err == nil {
+		t.Errorf("NewWrappedAppRoleAuth() expected error for used wrapping token")
+	}
+}
+
+func TestWrappedAppRoleAuth_loginError(t *testing.T) {
+	tests := []struct {
+		name       string
+		loginError string
+		want       bool
+	}{
+		{name: "invalid secret id", loginError: "invalid secret id", want: true},
+		{name: "invalid role or secret id", loginError: "invalid role or secret ID", want: true},
+		{name: "other bad request", loginError: "missing role_id", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeWrapping{creationPath: "auth/approle/role/acmevault/secret-id", loginError: tt.loginError, logins: 1}
+			client := newFakeWrappingClient(t, fake)
+
+			auth, err := NewWrappedAppRoleAuth(context.Background(), client, "role-id", "wrapping-token")
+			if err != nil {
+				t.Fatalf("NewWrappedAppRoleAuth() error = %v", err)
+			}
+
+			_, err = client.Auth().Login(context.Background(), auth)
+			if err == nil {
+				t.Fatal("expected error for rejected login")
+			}
+			if got := errors.Is(err, ErrSecretIdConsumed); got != tt.want {
+				t.Errorf("Login() error = %v, is ErrSecretIdConsumed = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrappedAppRoleAuth_unexpectedCreationPath(t *testing.T) {
+	fake := &fakeWrapping{creationPath: "secret/data/something"}
+	client := newFakeWrappingClient(t, fake)
+
+	if _, err := NewWrappedAppRoleAuth(context.Background(), client, "role-id", "wrapping-token"); err == nil {
+		t.Fatal("NewWrappedAppRoleAuth() expected error")
+	}
+	if fake.unwraps > 0 {
+		t.Errorf("token has been unwrapped despite unexpected creation path")
+	}
+}
+
+func TestWrappedAppRoleAuth_namespace(t *testing.T) {
+	fake := &fakeWrapping{creationPath: "auth/approle/role/acmevault/secret-id", namespaces: map[string]string{}}
+	client := newFakeWrappingClient(t, fake).WithNamespace("org/auth")
+
+	if _, err := NewWrappedAppRoleAuth(context.Background(), client, "role-id", "wrapping-token"); err != nil {
+		t.Fatalf("NewWrappedAppRoleAuth() error = %v", err)
+	}
+
+	for _, path := range []string{"/v1/sys/wrapping/lookup", "/v1/sys/wrapping/unwrap"} {
+		if got := fake.namespaces[path]; got != "org/auth" {
+			t.Errorf("namespace of %s = %q, want %q", path, got, "org/auth")
+		}
+	}
+}
+
+func Test_isSecretIdCreationPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "auth/approle/role/acmevault/secret-id", want: true},
+		{path: "auth/approle/role/acmevault/custom-secret-id", want: true},
+		{path: "auth/approle/role//secret-id", want: false},
+		{path: "auth/approle/role/acmevault/role-id", want: false},
+		{path: "auth/approle/role/acmevault/secret-id/lookup", want: false},
+		{path: "sys/wrapping/wrap", want: false},
+		{path: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := isSecretIdCreationPath(tt.path); got != tt.want {
+				t.Errorf("isSecretIdCreationPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultAwsAuthMountPath = "aws"
+	defaultAwsAuthRegion    = "us-east-1"
+
+	awsIamServerIdHeader = "X-Vault-AWS-IAM-Server-ID"
+	stsRequestBody       = "Action=GetCallerIdentity&Version=2011-06-15"
+)
+
+// AwsIamAuth logs in using the iam type of Vault's AWS auth method. It signs a sts:GetCallerIdentity request with the
+// ambient AWS credentials, e.g. from an instance profile or IRSA, which Vault then executes to verify the identity.
+type AwsIamAuth struct {
+	mountPath   string
+	role        string
+	serverId    string
+	region      string
+	credentials aws.CredentialsProvider
+}
+
+func NewAwsIamAuth(mountPath, role, serverId, region string, credentials aws.CredentialsProvider) (*AwsIamAuth, error) {
+	if credentials == nil {
+		return nil, errors.New("empty credentials provider passed")
+	}
+
+	if len(mountPath) == 0 {
+		mountPath = defaultAwsAuthMountPath
+	}
+
+	if len(region) == 0 {
+		region = defaultAwsAuthRegion
+	}
+
+	return &AwsIamAuth{
+		mountPath:   mountPath,
+		role:        role,
+		serverId:    serverId,
+		region:      region,
+		credentials: credentials,
+	}, nil
+}
+
+func (t *AwsIamAuth) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	data, err := t.buildLoginData(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("auth/%s/login", t.mountPath)
+	secret, err := client.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with aws auth: %w", err)
+	}
+
+	if secret == nil || secret.Auth == nil {
+		return nil, errors.New("empty response from aws auth login")
+	}
+
+	return secret, nil
+}
+
+// buildLoginData signs the sts:GetCallerIdentity request and encodes it as expected by Vault.
+func (t *AwsIamAuth) buildLoginData(ctx context.Context, now time.Time) (map[string]interface{}, error) {
+	creds, err := t.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve aws credentials: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.stsEndpoint(), bytes.NewBufferString(stsRequestBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	if len(t.serverId) > 0 {
+		req.Header.Set(awsIamServerIdHeader, t.serverId)
+	}
+
+	payloadHash := sha256.Sum256([]byte(stsRequestBody))
+	signer := v4.NewSigner()
+	if err := signer.SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), "sts", t.region, now); err != nil {
+		return nil, fmt.Errorf("could not sign sts request: %w", err)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := json.Marshal(req.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"iam_http_request_method": req.Method,
+		"iam_request_url":         base64.StdEncoding.EncodeToString([]byte(req.URL.String())),
+		"iam_request_headers":     base64.StdEncoding.EncodeToString(headers),
+		"iam_request_body":        base64.StdEncoding.EncodeToString(body),
+	}
+	if len(t.role) > 0 {
+		data["role"] = t.role
+	}
+
+	return data, nil
+}
+
+// stsEndpoint returns the endpoint the request is signed for, which has to match the sts endpoint configured in Vault.
+func (t *AwsIamAuth) stsEndpoint() string {
+	if t.region == defaultAwsAuthRegion {
+		return "https://sts.amazonaws.com/"
+	}
+	return fmt.Sprintf("https://sts.%s.amazonaws.com/", t.region)
+}
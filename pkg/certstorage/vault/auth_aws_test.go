@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/hashicorp/vault/api"
+)
+
+var staticAwsCredentials = aws.CredentialsProviderFunc(func(_ context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}, nil
+})
+
+func decodeLoginField(t *testing.T, data map[string]interface{}, key string) string {
+	t.Helper()
+	decoded, err := base64.StdEncoding.DecodeString(data[key].(string))
+	if err != nil {
+		t.Fatalf("could not decode %s: %v", key, err)
+	}
+	return string(decoded)
+}
+
+func TestAwsIamAuth_buildLoginData(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   string
+		serverId string
+		wantUrl  string
+	}{
+		{
+			name:    "global endpoint",
+			wantUrl: "https://sts.amazonaws.com/",
+		},
+		{
+			name:     "regional endpoint with server id",
+			region:   "eu-central-1",
+			serverId: "vault.example.com",
+			wantUrl:  "https://sts.eu-central-1.amazonaws.com/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := NewAwsIamAuth("", "acmevault", tt.serverId, tt.region, staticAwsCredentials)
+			if err != nil {
+				t.Fatalf("NewAwsIamAuth() error = %v", err)
+			}
+
+			now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			data, err := auth.buildLoginData(context.Background(), now)
+			if err != nil {
+				t.Fatalf("buildLoginData() error = %v", err)
+			}
+
+			if data["role"] != "acmevault" || data["iam_http_request_method"] != http.MethodPost {
+				t.Errorf("buildLoginData() got = %v", data)
+			}
+			if got := decodeLoginField(t, data, "iam_request_url"); got != tt.wantUrl {
+				t.Errorf("iam_request_url = %q, want %q", got, tt.wantUrl)
+			}
+			if got := decodeLoginField(t, data, "iam_request_body"); got != stsRequestBody {
+				t.Errorf("iam_request_body = %q, want %q", got, stsRequestBody)
+			}
+
+			var headers http.Header
+			if err := json.Unmarshal([]byte(decodeLoginField(t, data, "iam_request_headers")), &headers); err != nil {
+				t.Fatal(err)
+			}
+
+			region := tt.region
+			if len(region) == 0 {
+				region = defaultAwsAuthRegion
+			}
+			authorization := headers.Get("Authorization")
+			if !strings.Contains(authorization, "Credential=AKIDEXAMPLE/20240101/"+region+"/sts/aws4_request") {
+				t.Errorf("unexpected authorization header %q", authorization)
+			}
+			if headers.Get("X-Amz-Security-Token") != "session" {
+				t.Errorf("missing session token header")
+			}
+			if got := headers.Get(awsIamServerIdHeader); got != tt.serverId {
+				t.Errorf("server id header = %q, want %q", got, tt.serverId)
+			}
+			if len(tt.serverId) > 0 && !strings.Contains(authorization, "x-vault-aws-iam-server-id") {
+				t.Errorf("server id header is not signed: %q", authorization)
+			}
+		})
+	}
+}
+
+func TestAwsIamAuth_Login(t *testing.T) {
+	var loginPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loginPath = r.URL.Path
+		writeJson(w, map[string]interface{}{"auth": map[string]interface{}{"client_token": "token"}})
+	}))
+	defer server.Close()
+
+	conf := api.DefaultConfig()
+	conf.Address = server.URL
+	client, err := api.NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth, err := NewAwsIamAuth("aws-prod", "acmevault", "", "", staticAwsCredentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Auth().Login(context.Background(), auth); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if loginPath != "/v1/auth/aws-prod/login" {
+		t.Errorf("login path = %q", loginPath)
+	}
+	if client.Token() != "token" {
+		t.Errorf("Login() did not set token on client")
+	}
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultCertAuthMountPath = "cert"
+
+// CertAuth logs in using Vault's TLS certificate auth method. The certificate is read from disk and reloaded when it
+// changes, so a machine certificate that is rotated by acmevault itself is picked up with the next login.
+type CertAuth struct {
+	mountPath string
+	role      string
+	reloader  *ClientCertReloader
+}
+
+func NewCertAuth(mountPath, role, certFile, keyFile string) (*CertAuth, error) {
+	reloader, err := NewClientCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(mountPath) == 0 {
+		mountPath = defaultCertAuthMountPath
+	}
+
+	return &CertAuth{
+		mountPath: mountPath,
+		role:      role,
+		reloader:  reloader,
+	}, nil
+}
+
+func (t *CertAuth) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	loginClient, err := t.buildLoginClient(client)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	if len(t.role) > 0 {
+		data["name"] = t.role
+	}
+
+	path := fmt.Sprintf("auth/%s/login", t.mountPath)
+	secret, err := loginClient.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with cert auth: %w", err)
+	}
+
+	if secret == nil || secret.Auth == nil {
+		return nil, errors.New("empty response from cert auth login")
+	}
+
+	return secret, nil
+}
+
+// buildLoginClient returns a copy of the client that presents the certificate during the TLS handshake, leaving the
+// transport of the original client untouched.
+func (t *CertAuth) buildLoginClient(client *api.Client) (*api.Client, error) {
+	conf := client.CloneConfig()
+	transport, ok := conf.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unsupported http transport %T", conf.HttpClient.Transport)
+	}
+
+	transport = transport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	transport.TLSClientConfig.GetClientCertificate = t.reloader.GetClientCertificate
+	conf.HttpClient.Transport = transport
+
+	loginClient, err := api.NewClient(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	loginClient.ClearToken()
+	if namespace := client.Namespace(); len(namespace) > 0 {
+		loginClient.SetNamespace(namespace)
+	} else {
+		loginClient.ClearNamespace()
+	}
+
+	return loginClient, nil
+}
@@ -0,0 +1,98 @@
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestCertAuth_Login(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeClientCert(t, certFile, keyFile, "acmevault", time.Now())
+
+	type request struct {
+		path       string
+		commonName string
+		token      string
+		body       map[string]interface{}
+	}
+	var mutex sync.Mutex
+	var requests []request
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{path: r.URL.Path, token: r.Header.Get("X-Vault-Token")}
+		if len(r.TLS.PeerCertificates) > 0 {
+			req.commonName = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req.body)
+
+		mutex.Lock()
+		requests = append(requests, req)
+		mutex.Unlock()
+
+		writeJson(w, map[string]interface{}{"auth": map[string]interface{}{"client_token": "token", "renewable": true}})
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	conf := api.DefaultConfig()
+	conf.Address = server.URL
+	if err := conf.ConfigureTLS(&api.TLSConfig{Insecure: true}); err != nil {
+		t.Fatal(err)
+	}
+	client, err := api.NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("old-token")
+
+	auth, err := NewCertAuth("", "acmevault", certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewCertAuth() error = %v", err)
+	}
+
+	if _, err := client.Auth().Login(context.Background(), auth); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if client.Token() != "token" {
+		t.Errorf("Login() did not set token on client")
+	}
+
+	// the original client must not present the certificate
+	if _, err := client.Logical().Read("secret/data/test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+
+	login := requests[0]
+	if login.path != "/v1/auth/cert/login" {
+		t.Errorf("login path = %q, want %q", login.path, "/v1/auth/cert/login")
+	}
+	if login.commonName != "acmevault" {
+		t.Errorf("login presented cert %q, want %q", login.commonName, "acmevault")
+	}
+	if len(login.token) > 0 {
+		t.Errorf("login sent token %q", login.token)
+	}
+	if login.body["name"] != "acmevault" {
+		t.Errorf("login body = %v, want role name", login.body)
+	}
+
+	if requests[1].commonName != "" {
+		t.Errorf("original client presented cert %q", requests[1].commonName)
+	}
+}
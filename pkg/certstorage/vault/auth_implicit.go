@@ -2,9 +2,11 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/rs/zerolog/log"
@@ -59,8 +61,11 @@ func (t *ImplicitAuth) getToken() (string, error) {
 		tokenFile := expandPath(file)
 		log.Info().Msgf("Trying vault token from file '%s'", tokenFile)
 		read, err := os.ReadFile(tokenFile)
+		if err == nil && len(strings.TrimSpace(string(read))) == 0 {
+			err = errors.New("empty token file")
+		}
 		if err == nil {
-			return string(read), nil
+			return strings.TrimSpace(string(read)), nil
 		}
 		log.Warn().Msgf("Specified token file %s could not be read: %v", tokenFile, err)
 		errs = multierr.Append(errs, err)
@@ -0,0 +1,86 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const defaultJwtAuthMountPath = "jwt"
+
+// JwtAuth logs in using Vault's JWT/OIDC auth method. The JWT is read from a file on every login, so short-lived
+// tokens that are rotated on disk, e.g. projected service account tokens or SPIFFE JWT-SVIDs, are picked up when the
+// token renewer logs in again.
+type JwtAuth struct {
+	mountPath string
+	role      string
+	jwtFile   string
+}
+
+func NewJwtAuth(mountPath, role, jwtFile string) (*JwtAuth, error) {
+	if len(role) == 0 {
+		return nil, errors.New("empty role passed")
+	}
+
+	if len(jwtFile) == 0 {
+		return nil, errors.New("empty jwt file passed")
+	}
+
+	if len(mountPath) == 0 {
+		mountPath = defaultJwtAuthMountPath
+	}
+
+	return &JwtAuth{
+		mountPath: mountPath,
+		role:      role,
+		jwtFile:   jwtFile,
+	}, nil
+}
+
+func (t *JwtAuth) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	jwt, err := t.readJwt()
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"role": t.role,
+		"jwt":  jwt,
+	}
+
+	path := fmt.Sprintf("auth/%s/login", t.mountPath)
+	secret, err := client.Logical().WriteWithContext(ctx, path, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in with jwt auth: %w", err)
+	}
+
+	if secret == nil || secret.Auth == nil {
+		return nil, errors.New("empty response from jwt auth login")
+	}
+
+	return secret, nil
+}
+
+// delayRelogin makes the token renewer use non-renewable tokens for most of their ttl instead of logging in again
+// immediately, the next login reads the JWT that has been rotated in the meantime.
+func (t *JwtAuth) delayRelogin() bool {
+	return true
+}
+
+func (t *JwtAuth) readJwt() (string, error) {
+	data, err := os.ReadFile(t.jwtFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read jwt from %s: %w", t.jwtFile, err)
+	}
+
+	jwt := strings.TrimSpace(string(data))
+	if len(jwt) == 0 {
+		return "", fmt.Errorf("empty jwt in %s", t.jwtFile)
+	}
+
+	return jwt, nil
+}
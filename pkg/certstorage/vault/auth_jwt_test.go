@@ -0,0 +1,67 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestJwtAuth_Login(t *testing.T) {
+	var logins []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/gitlab/login" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		logins = append(logins, body)
+		writeJson(w, map[string]interface{}{"auth": map[string]interface{}{"client_token": "token"}})
+	}))
+	defer server.Close()
+
+	conf := api.DefaultConfig()
+	conf.Address = server.URL
+	client, err := api.NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	auth, err := NewJwtAuth("gitlab", "acmevault", jwtFile)
+	if err != nil {
+		t.Fatalf("NewJwtAuth() error = %v", err)
+	}
+
+	if _, err := client.Auth().Login(context.Background(), auth); err == nil {
+		t.Fatal("Login() expected error for missing jwt file")
+	}
+
+	for _, jwt := range []string{"first-jwt", "rotated-jwt"} {
+		if err := os.WriteFile(jwtFile, []byte(jwt+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Auth().Login(context.Background(), auth); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+	}
+
+	if len(logins) != 2 {
+		t.Fatalf("expected 2 logins, got %d", len(logins))
+	}
+	if logins[0]["jwt"] != "first-jwt" || logins[1]["jwt"] != "rotated-jwt" {
+		t.Errorf("Login() sent jwts %v, %v", logins[0]["jwt"], logins[1]["jwt"])
+	}
+	if logins[1]["role"] != "acmevault" {
+		t.Errorf("Login() sent role %v, want acmevault", logins[1]["role"])
+	}
+	if client.Token() != "token" {
+		t.Errorf("Login() did not set token on client")
+	}
+}
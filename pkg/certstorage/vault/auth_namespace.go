@@ -0,0 +1,34 @@
+package vault
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// NamespacedAuth logs in using the wrapped auth method in a namespace that differs from the client's namespace,
+// e.g. if auth mounts and secrets mounts live in different namespaces.
+type NamespacedAuth struct {
+	auth      api.AuthMethod
+	namespace string
+}
+
+func NewNamespacedAuth(auth api.AuthMethod, namespace string) (*NamespacedAuth, error) {
+	if auth == nil {
+		return nil, errors.New("empty authmethod passed")
+	}
+
+	return &NamespacedAuth{
+		auth:      auth,
+		namespace: namespace,
+	}, nil
+}
+
+func (t *NamespacedAuth) delayRelogin() bool {
+	return delaysRelogin(t.auth)
+}
+
+func (t *NamespacedAuth) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	return t.auth.Login(ctx, client.WithNamespace(t.namespace))
+}
@@ -0,0 +1,60 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+type namespaceRecordingAuth struct {
+	namespace string
+}
+
+func (a *namespaceRecordingAuth) Login(_ context.Context, client *api.Client) (*api.Secret, error) {
+	a.namespace = client.Namespace()
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: "token"}}, nil
+}
+
+func TestNamespacedAuth_Login(t *testing.T) {
+	var namespaces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespaces = append(namespaces, r.Header.Get("X-Vault-Namespace"))
+		writeJson(w, map[string]interface{}{"data": map[string]interface{}{"key": "value"}})
+	}))
+	defer server.Close()
+
+	conf := api.DefaultConfig()
+	conf.Address = server.URL
+	client, err := api.NewClient(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetNamespace("org/secrets")
+
+	wrapped := &namespaceRecordingAuth{}
+	auth, err := NewNamespacedAuth(wrapped, "org/auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Auth().Login(context.Background(), auth); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if wrapped.namespace != "org/auth" {
+		t.Errorf("Login() used namespace %q, want %q", wrapped.namespace, "org/auth")
+	}
+	if client.Token() != "token" {
+		t.Errorf("Login() did not set token on client")
+	}
+
+	// requests after the login still use the namespace of the secrets
+	if _, err := client.Logical().Read("secret/data/test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(namespaces) != 1 || namespaces[0] != "org/secrets" {
+		t.Errorf("requests used namespaces %v, want [org/secrets]", namespaces)
+	}
+}
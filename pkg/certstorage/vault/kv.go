@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/config"
+)
+
+const (
+	kvVersion1 = 1
+	kvVersion2 = 2
+)
+
+// kvStore abstracts the differences between the versions of Vault's KV secrets engine.
+type kvStore interface {
+	Put(ctx context.Context, path string, data map[string]interface{}) error
+	Get(ctx context.Context, path string) (map[string]interface{}, error)
+}
+
+type kv1Store struct {
+	kv *api.KVv1
+}
+
+func (s *kv1Store) Put(ctx context.Context, path string, data map[string]interface{}) error {
+	return s.kv.Put(ctx, path, data)
+}
+
+func (s *kv1Store) Get(ctx context.Context, path string) (map[string]interface{}, error) {
+	secret, err := s.kv.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.Data, nil
+}
+
+type kv2Store struct {
+	kv *api.KVv2
+}
+
+func (s *kv2Store) Put(ctx context.Context, path string, data map[string]interface{}) error {
+	_, err := s.kv.Put(ctx, path, data)
+	return err
+}
+
+func (s *kv2Store) Get(ctx context.Context, path string) (map[string]interface{}, error) {
+	secret, err := s.kv.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret.Data, nil
+}
+
+func newKvStore(client *api.Client, mountPath string, version int) (kvStore, error) {
+	switch version {
+	case kvVersion1:
+		return &kv1Store{kv: client.KVv1(mountPath)}, nil
+	case kvVersion2:
+		return &kv2Store{kv: client.KVv2(mountPath)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kv version %d", version)
+	}
+}
+
+// detectKvVersion looks up the version of the KV secrets engine mounted at the given path. It uses the same
+// endpoint as the Vault CLI, which does not require access to sys/mounts.
+func detectKvVersion(ctx context.Context, client *api.Client, mountPath string) (int, error) {
+	secret, err := client.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+mountPath)
+	if err != nil {
+		return 0, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return 0, fmt.Errorf("no mount info for %s", mountPath)
+	}
+
+	// mounts without a version option are not guessed, as writing v1 secrets to a v2 mount or vice versa fails
+	options, _ := secret.Data["options"].(map[string]interface{})
+	version, _ := options["version"].(string)
+	switch version {
+	case "1":
+		return kvVersion1, nil
+	case "2":
+		return kvVersion2, nil
+	default:
+		return 0, fmt.Errorf("mount %s does not report a kv version, configure it explicitly", mountPath)
+	}
+}
+
+// kv returns the kv store, detecting the version of the secrets engine on first use if configured to do so. The
+// detection is deferred until the first request, as the client is not authenticated when the backend is built. A
+// failed detection is not cached, so it's retried with the next request.
+func (vault *VaultBackend) kv(ctx context.Context) (kvStore, error) {
+	vault.kvMutex.Lock()
+	defer vault.kvMutex.Unlock()
+
+	if vault.kvStore != nil {
+		return vault.kvStore, nil
+	}
+
+	var version int
+	switch vault.conf.KvVersion {
+	case config.KvVersion1:
+		version = kvVersion1
+	case config.KvVersionAuto:
+		var err error
+		version, err = detectKvVersion(ctx, vault.client, vault.conf.Kv2MountPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not detect kv version of mount %q: %w", vault.conf.Kv2MountPath, err)
+		}
+		log.Info().Msgf("Detected kv version %d for mount %q", version, vault.conf.Kv2MountPath)
+	default:
+		version = kvVersion2
+	}
+
+	store, err := newKvStore(vault.client, vault.conf.Kv2MountPath, version)
+	if err != nil {
+		return nil, err
+	}
+
+	vault.kvStore = store
+	return store, nil
+}
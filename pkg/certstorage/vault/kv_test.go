@@ -0,0 +1,201 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/soerenschneider/acmevault/internal/config"
+	"github.com/soerenschneider/acmevault/pkg/certstorage"
+)
+
+// fakeKv emulates a KV secrets engine of the given version mounted at 'secret'.
+type fakeKv struct {
+	version int
+	mutex   sync.Mutex
+	data    map[string]map[string]interface{}
+	// hideVersion omits the version option of the mount
+	hideVersion bool
+}
+
+func (f *fakeKv) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.URL.Path == "/v1/sys/internal/ui/mounts/secret" {
+		options := map[string]interface{}{}
+		if !f.hideVersion {
+			options["version"] = strconv.Itoa(f.version)
+		}
+		writeJson(w, map[string]interface{}{"data": map[string]interface{}{"type": "kv", "options": options}})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/secret/")
+	if f.version == kvVersion2 {
+		if !strings.HasPrefix(path, "data/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		path = strings.TrimPrefix(path, "data/")
+	}
+
+	metadata := map[string]interface{}{"created_time": "2024-01-01T00:00:00Z", "version": 1}
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if f.version == kvVersion2 {
+			f.data[path] = body["data"].(map[string]interface{})
+			writeJson(w, map[string]interface{}{"data": metadata})
+			return
+		}
+		f.data[path] = body
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		data, ok := f.data[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			writeJson(w, map[string]interface{}{"errors": []string{}})
+			return
+		}
+		if f.version == kvVersion2 {
+			writeJson(w, map[string]interface{}{"data": map[string]interface{}{"data": data, "metadata": metadata}})
+			return
+		}
+		writeJson(w, map[string]interface{}{"data": data})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJson(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func newFakeVaultBackend(t *testing.T, serverVersion int, configuredVersion string) *VaultBackend {
+	t.Helper()
+	server := httptest.NewServer(&fakeKv{version: serverVersion, data: map[string]map[string]interface{}{}})
+	t.Cleanup(server.Close)
+
+	vaultConf := api.DefaultConfig()
+	vaultConf.Address = server.URL
+	client, err := api.NewClient(vaultConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken("token")
+
+	backend, err := NewVaultBackend(client, config.VaultConfig{
+		PathPrefix:   "prod",
+		Kv2MountPath: "secret",
+		KvVersion:    configuredVersion,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend
+}
+
+func TestVaultBackend_kvVersions(t *testing.T) {
+	tests := []struct {
+		name              string
+		serverVersion     int
+		configuredVersion string
+	}{
+		{
+			name:          "kv2 by default",
+			serverVersion: kvVersion2,
+		},
+		{
+			name:              "kv1 detected",
+			serverVersion:     kvVersion1,
+			configuredVersion: config.KvVersionAuto,
+		},
+		{
+			name:              "kv2 detected",
+			serverVersion:     kvVersion2,
+			configuredVersion: config.KvVersionAuto,
+		},
+		{
+			name:              "kv1 configured",
+			serverVersion:     kvVersion1,
+			configuredVersion: config.KvVersion1,
+		},
+		{
+			name:              "kv2 configured",
+			serverVersion:     kvVersion2,
+			configuredVersion: config.KvVersion2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeVaultBackend(t, tt.serverVersion, tt.configuredVersion)
+
+			if _, err := backend.ReadPublicCertificateData("example.com"); !errors.Is(err, certstorage.ErrNotFound) {
+				t.Fatalf("ReadPublicCertificateData() error = %v, want %v", err, certstorage.ErrNotFound)
+			}
+
+			cert := &certstorage.AcmeCertificate{
+				Domain:            "example.com",
+				CertURL:           "https://acme/cert/1",
+				CertStableURL:     "https://acme/cert/1/stable",
+				PrivateKey:        []byte("private key"),
+				Certificate:       []byte("certificate"),
+				IssuerCertificate: []byte("issuer"),
+			}
+			if err := backend.WriteCertificate(cert); err != nil {
+				t.Fatalf("WriteCertificate() error = %v", err)
+			}
+
+			got, err := backend.ReadFullCertificateData("example.com")
+			if err != nil {
+				t.Fatalf("ReadFullCertificateData() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.PrivateKey, []byte("private key")) || !reflect.DeepEqual(got.Certificate, []byte("certificate")) {
+				t.Errorf("ReadFullCertificateData() got = %v", got)
+			}
+		})
+	}
+}
+
+func TestDetectKvVersion(t *testing.T) {
+	for _, version := range []int{kvVersion1, kvVersion2} {
+		backend := newFakeVaultBackend(t, version, config.KvVersionAuto)
+		got, err := detectKvVersion(context.Background(), backend.client, "secret")
+		if err != nil {
+			t.Fatalf("detectKvVersion() error = %v", err)
+		}
+		if got != version {
+			t.Errorf("detectKvVersion() got = %v, want %v", got, version)
+		}
+	}
+}
+
+func TestDetectKvVersion_missingVersion(t *testing.T) {
+	server := httptest.NewServer(&fakeKv{version: kvVersion2, hideVersion: true, data: map[string]map[string]interface{}{}})
+	defer server.Close()
+
+	vaultConf := api.DefaultConfig()
+	vaultConf.Address = server.URL
+	client, err := api.NewClient(vaultConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version, err := detectKvVersion(context.Background(), client, "secret"); err == nil {
+		t.Errorf("detectKvVersion() expected error, got version %d", version)
+	}
+}
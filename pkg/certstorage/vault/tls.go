@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ClientCertReloader provides the client certificate for mTLS connections to Vault and reloads it as soon as the
+// certificate or key file change on disk, so rotated certificates are picked up without restarting.
+type ClientCertReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex       sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func NewClientCertReloader(certFile, keyFile string) (*ClientCertReloader, error) {
+	if len(certFile) == 0 || len(keyFile) == 0 {
+		return nil, errors.New("empty cert or key file passed")
+	}
+
+	reloader := &ClientCertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	if _, err := reloader.getCertificate(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+// GetClientCertificate satisfies tls.Config.GetClientCertificate.
+func (r *ClientCertReloader) GetClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.getCertificate()
+}
+
+func (r *ClientCertReloader) getCertificate() (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		if r.cert != nil {
+			log.Warn().Err(err).Msg("Could not check vault client certificate for changes, using cached certificate")
+			return r.cert, nil
+		}
+		return nil, err
+	}
+
+	if r.cert != nil && certModTime.Equal(r.certModTime) && keyModTime.Equal(r.keyModTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// cert and key are usually not replaced atomically, keep using the old pair until both match
+		if r.cert != nil {
+			log.Warn().Err(err).Msg("Could not reload vault client certificate, using cached certificate")
+			return r.cert, nil
+		}
+		return nil, fmt.Errorf("could not load vault client certificate: %w", err)
+	}
+
+	if r.cert != nil {
+		log.Info().Msgf("Reloaded vault client certificate from %s", r.certFile)
+	}
+
+	r.cert = &cert
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+	return r.cert, nil
+}
+
+func (r *ClientCertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
@@ -0,0 +1,103 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeClientCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func getCommonName(t *testing.T, reloader *ClientCertReloader) string {
+	t.Helper()
+	cert, err := reloader.GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("GetClientCertificate() error = %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestClientCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	now := time.Now()
+
+	if _, err := NewClientCertReloader(certFile, keyFile); err == nil {
+		t.Fatal("NewClientCertReloader() expected error for missing files")
+	}
+
+	writeClientCert(t, certFile, keyFile, "first", now.Add(-time.Hour))
+	reloader, err := NewClientCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("NewClientCertReloader() error = %v", err)
+	}
+
+	if got := getCommonName(t, reloader); got != "first" {
+		t.Errorf("GetClientCertificate() got %q, want %q", got, "first")
+	}
+
+	writeClientCert(t, certFile, keyFile, "second", now)
+	if got := getCommonName(t, reloader); got != "second" {
+		t.Errorf("GetClientCertificate() after rotation got %q, want %q", got, "second")
+	}
+
+	// a half-written rotation keeps the previous certificate
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(keyFile, now.Add(time.Hour), now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := getCommonName(t, reloader); got != "second" {
+		t.Errorf("GetClientCertificate() with broken key got %q, want %q", got, "second")
+	}
+}
@@ -15,8 +15,22 @@ import (
 const (
 	vaultTokenRenewerComponent = "token-renewer"
 	logComponent               = "token_rewewer"
+
+	minReloginWait = 15 * time.Second
 )
 
+// delayedReloginAuth is implemented by auth methods whose non-renewable tokens are used for most of their ttl before
+// logging in again, instead of logging in again immediately.
+type delayedReloginAuth interface {
+	delayRelogin() bool
+}
+
+// delaysRelogin returns whether the given auth method wants to delay logging in again with a non-renewable token.
+func delaysRelogin(auth vault.AuthMethod) bool {
+	delayed, ok := auth.(delayedReloginAuth)
+	return ok && delayed.delayRelogin()
+}
+
 type TokenRenewer struct {
 	client *vault.Client
 	auth   vault.AuthMethod
@@ -46,6 +60,14 @@ func (t *TokenRenewer) StartTokenRenewal(ctx context.Context, wg *sync.WaitGroup
 			log.Info().Str("component", vaultTokenRenewerComponent).Msg("Logging in to VaultId")
 			vaultLoginResp, err := t.client.Auth().Login(ctx, t.auth)
 
+			if errors.Is(err, ErrSecretIdConsumed) {
+				// logging in again can not succeed without a new secret id
+				log.Error().Str("component", vaultTokenRenewerComponent).Err(err).Msg("Giving up logging in to Vault")
+				metrics.VaultLoginErrors.Inc()
+				vaultAuthError <- err
+				return
+			}
+
 			if err != nil {
 				var respErr *vault.ResponseError
 				if errors.As(err, &respErr) {
@@ -66,7 +88,7 @@ func (t *TokenRenewer) StartTokenRenewal(ctx context.Context, wg *sync.WaitGroup
 			}
 			metrics.VaultLogins.Inc()
 
-			tokenErr := manageTokenLifecycle(ctx, t.client, vaultLoginResp)
+			tokenErr := manageTokenLifecycle(ctx, t.client, vaultLoginResp, delaysRelogin(t.auth))
 			if tokenErr != nil {
 				metrics.VaultTokenRenewErrors.Inc()
 				log.Error().Str(logComponent, vaultTokenRenewerComponent).Err(err).Msgf("unable to start managing token lifecycle")
@@ -79,13 +101,25 @@ func (t *TokenRenewer) StartTokenRenewal(ctx context.Context, wg *sync.WaitGroup
 
 // Starts token lifecycle management. Returns only fatal errors as errors,
 // otherwise returns nil so we can attempt login again.
-func manageTokenLifecycle(ctx context.Context, client *vault.Client, token *vault.Secret) error {
+func manageTokenLifecycle(ctx context.Context, client *vault.Client, token *vault.Secret, delayRelogin bool) error {
 	renew := token.Auth.Renewable // You may notice a different top-level field called Renewable. That one is used for dynamic secrets renewal, not token renewal.
-	if !renew {
+	if !renew && !delayRelogin {
 		log.Warn().Msg("Token is not configured to be renewable. Re-attempting login.")
 		return nil
 	}
 
+	if !renew {
+		// wait until most of the token's ttl has passed instead of logging in again immediately, the new login picks
+		// up the rotated JWT
+		wait := nonRenewableTokenWait(token.Auth.LeaseDuration)
+		log.Warn().Msgf("Token is not configured to be renewable. Re-attempting login in %v.", wait)
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+		return nil
+	}
+
 	watcher, err := client.NewLifetimeWatcher(&vault.LifetimeWatcherInput{
 		Secret:    token,
 		Increment: 3600, // Learn more about this optional value in https://www.vaultproject.io/docs/concepts/lease#lease-durations-and-renewal
@@ -121,3 +155,9 @@ func manageTokenLifecycle(ctx context.Context, client *vault.Client, token *vaul
 		}
 	}
 }
+
+// nonRenewableTokenWait returns how long to wait before logging in again with a token that can not be renewed.
+func nonRenewableTokenWait(leaseDuration int) time.Duration {
+	wait := time.Duration(leaseDuration) * time.Second * 2 / 3
+	return max(wait, minReloginWait)
+}
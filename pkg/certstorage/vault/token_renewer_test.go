@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestNonRenewableTokenWait(t *testing.T) {
+	tests := []struct {
+		leaseDuration int
+		want          time.Duration
+	}{
+		{leaseDuration: 0, want: minReloginWait},
+		{leaseDuration: 3, want: minReloginWait},
+		{leaseDuration: 3600, want: 40 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := nonRenewableTokenWait(tt.leaseDuration); got != tt.want {
+			t.Errorf("nonRenewableTokenWait(%d) = %v, want %v", tt.leaseDuration, got, tt.want)
+		}
+	}
+}
+
+func TestDelaysRelogin(t *testing.T) {
+	jwt, err := NewJwtAuth("", "acmevault", "/run/secrets/jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespacedJwt, err := NewNamespacedAuth(jwt, "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := NewTokenAuth("hvs.token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespacedToken, err := NewNamespacedAuth(token, "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		auth api.AuthMethod
+		want bool
+	}{
+		{name: "jwt", auth: jwt, want: true},
+		{name: "namespaced jwt", auth: namespacedJwt, want: true},
+		{name: "token", auth: token, want: false},
+		{name: "namespaced token", auth: namespacedToken, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := delaysRelogin(tt.auth); got != tt.want {
+				t.Errorf("delaysRelogin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManageTokenLifecycle_nonRenewable(t *testing.T) {
+	token := &api.Secret{Auth: &api.SecretAuth{Renewable: false, LeaseDuration: 3600}}
+
+	// auth methods that don't delay the login again return immediately
+	if err := manageTokenLifecycle(context.Background(), nil, token, false); err != nil {
+		t.Fatalf("manageTokenLifecycle() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := manageTokenLifecycle(ctx, nil, token, true); err != nil {
+		t.Fatalf("manageTokenLifecycle() error = %v", err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("expected manageTokenLifecycle() to wait before logging in again")
+	}
+}
+
+func TestTokenRenewer_secretIdConsumed(t *testing.T) {
+	// the secret id has already been used for a login
+	fake := &fakeWrapping{creationPath: "auth/approle/role/acmevault/secret-id", logins: 1}
+	client := newFakeWrappingClient(t, fake)
+	auth, err := NewWrappedAppRoleAuth(context.Background(), client, "role-id", "wrapping-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renewer, err := NewTokenRenewer(client, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ready := &sync.WaitGroup{}
+	ready.Add(1)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		renewer.StartTokenRenewal(context.Background(), ready, errs)
+		close(done)
+	}()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrSecretIdConsumed) {
+			t.Errorf("StartTokenRenewal() error = %v, want %v", err, ErrSecretIdConsumed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected fatal error")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("expected StartTokenRenewal() to give up logging in")
+	}
+}
@@ -0,0 +1,123 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hashicorp/vault/api"
+	"github.com/rs/zerolog/log"
+	"github.com/soerenschneider/acmevault/internal/metrics"
+	"go.uber.org/multierr"
+)
+
+const vaultTokenWatcherComponent = "token-watcher"
+
+// TokenFileWatcher watches the token locations of the implicit auth method and swaps the token of the client whenever
+// an external process, e.g. a Vault Agent sink, rewrites a token file. The parent directories are watched instead of
+// the files themselves, as atomic writes replace the file and would silently end a watch on the file.
+type TokenFileWatcher struct {
+	client *api.Client
+	auth   *ImplicitAuth
+}
+
+func NewTokenFileWatcher(client *api.Client, auth *ImplicitAuth) (*TokenFileWatcher, error) {
+	if client == nil {
+		return nil, errors.New("empty client passed")
+	}
+
+	if auth == nil {
+		return nil, errors.New("empty auth passed")
+	}
+
+	return &TokenFileWatcher{
+		client: client,
+		auth:   auth,
+	}, nil
+}
+
+// Start sets the current token on the client and keeps watching the token files in the background until the context
+// is cancelled.
+func (w *TokenFileWatcher) Start(ctx context.Context) error {
+	token, err := w.auth.getToken()
+	if err != nil {
+		return err
+	}
+	w.client.SetToken(token)
+
+	if len(os.Getenv(api.EnvVaultToken)) > 0 {
+		log.Info().Str(logComponent, vaultTokenWatcherComponent).Msgf("Token read from %s, not watching token files", api.EnvVaultToken)
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not build file watcher: %w", err)
+	}
+
+	files := map[string]struct{}{}
+	var errs error
+	for _, location := range w.auth.tokenLocations {
+		file := filepath.Clean(expandPath(location))
+		files[file] = struct{}{}
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			errs = multierr.Append(errs, err)
+			continue
+		}
+		log.Info().Str(logComponent, vaultTokenWatcherComponent).Msgf("Watching token file '%s'", file)
+	}
+
+	if len(watcher.WatchList()) == 0 {
+		_ = watcher.Close()
+		return fmt.Errorf("could not watch any token file: %w", errs)
+	}
+
+	go w.watch(ctx, watcher, files)
+	return nil
+}
+
+func (w *TokenFileWatcher) watch(ctx context.Context, watcher *fsnotify.Watcher, files map[string]struct{}) {
+	defer watcher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if _, found := files[filepath.Clean(event.Name)]; !found || event.Has(fsnotify.Chmod) {
+				continue
+			}
+			w.swapToken()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Error().Str(logComponent, vaultTokenWatcherComponent).Err(err).Msg("Error while watching token files")
+		}
+	}
+}
+
+// swapToken re-reads the token files and sets the token on the client if it has changed. If no token can be read, e.g.
+// because the file has just been truncated, the current token is kept.
+func (w *TokenFileWatcher) swapToken() {
+	token, err := w.auth.getToken()
+	if err != nil {
+		log.Warn().Str(logComponent, vaultTokenWatcherComponent).Err(err).Msg("Could not read changed token file, keeping current token")
+		metrics.VaultTokenSwapErrors.Inc()
+		return
+	}
+
+	if token == w.client.Token() {
+		return
+	}
+
+	log.Info().Str(logComponent, vaultTokenWatcherComponent).Msg("Token file changed, swapping token")
+	w.client.SetToken(token)
+	metrics.VaultTokenSwaps.Inc()
+}
@@ -0,0 +1,91 @@
+package vault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/soerenschneider/acmevault/internal/metrics"
+)
+
+func waitForToken(t *testing.T, client *api.Client, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if client.Token() == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("token = %q, want %q", client.Token(), want)
+}
+
+func TestTokenFileWatcher(t *testing.T) {
+	t.Setenv(api.EnvVaultToken, "")
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.ClearToken()
+
+	auth, err := NewImplicitAuth(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := NewTokenFileWatcher(client, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := watcher.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if client.Token() != "first" {
+		t.Fatalf("Start() did not set initial token, got %q", client.Token())
+	}
+
+	swaps := testutil.ToFloat64(metrics.VaultTokenSwaps)
+
+	// rewrite the file in place
+	if err := os.WriteFile(tokenFile, []byte("second\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	waitForToken(t, client, "second")
+
+	// atomically replace the file
+	tmp := filepath.Join(dir, ".token.tmp")
+	if err := os.WriteFile(tmp, []byte("third"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, tokenFile); err != nil {
+		t.Fatal(err)
+	}
+	waitForToken(t, client, "third")
+
+	if got := testutil.ToFloat64(metrics.VaultTokenSwaps) - swaps; got != 2 {
+		t.Errorf("token swaps = %v, want 2", got)
+	}
+
+	// a truncated file must not clear the token
+	if err := os.WriteFile(tokenFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if client.Token() != "third" {
+		t.Errorf("token = %q after truncating file, want %q", client.Token(), "third")
+	}
+}
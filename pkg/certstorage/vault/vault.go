@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -27,6 +28,9 @@ type VaultBackend struct {
 	client   *api.Client
 	conf     config.VaultConfig
 	basePath string
+
+	kvStore kvStore
+	kvMutex sync.Mutex
 }
 
 func NewVaultBackend(vaultClient *api.Client, vaultConfig config.VaultConfig) (*VaultBackend, error) {
@@ -47,7 +51,7 @@ func (vault *VaultBackend) WriteCertificate(resource *certstorage.AcmeCertificat
 
 	data := certstorage.CertToMap(resource)
 	certPath := vault.getCertDataPath(resource.Domain)
-	err := vault.writeKv2Secret(certPath, data)
+	err := vault.writeKvSecret(certPath, data)
 	if err != nil {
 		return fmt.Errorf("could not write certificate data for %s: %v", resource.Domain, err)
 	}
@@ -56,7 +60,7 @@ func (vault *VaultBackend) WriteCertificate(resource *certstorage.AcmeCertificat
 		"private_key": privateKey,
 	}
 	secretPath := vault.getSecretDataPath(resource.Domain)
-	err = vault.writeKv2Secret(secretPath, data)
+	err = vault.writeKvSecret(secretPath, data)
 	if err != nil {
 		return fmt.Errorf("could not write secrete data for domain %s: %v", resource.Domain, err)
 	}
@@ -66,9 +70,9 @@ func (vault *VaultBackend) WriteCertificate(resource *certstorage.AcmeCertificat
 
 func (vault *VaultBackend) ReadPublicCertificateData(domain string) (*certstorage.AcmeCertificate, error) {
 	certPath := vault.getCertDataPath(domain)
-	data, err := vault.readKv2Secret(certPath)
+	data, err := vault.readKvSecret(certPath)
 	if err != nil {
-		return nil, fmt.Errorf("could not readKv2Secret public cert data from vault for domain %s: %v", domain, err)
+		return nil, fmt.Errorf("could not read public cert data from vault for domain %s: %w", domain, err)
 	}
 	return certstorage.MapToCert(data)
 }
@@ -80,14 +84,14 @@ func (vault *VaultBackend) ReadFullCertificateData(domain string) (*certstorage.
 	}
 
 	privateKeyPath := vault.getSecretDataPath(domain)
-	data, err := vault.readKv2Secret(privateKeyPath)
+	data, err := vault.readKvSecret(privateKeyPath)
 	if err != nil {
-		return nil, fmt.Errorf("could not readKv2Secret private data from vault for domain %s: %v", domain, err)
+		return nil, fmt.Errorf("could not read private data from vault for domain %s: %w", domain, err)
 	}
 
 	_, ok := data["private_key"]
 	if !ok {
-		return nil, fmt.Errorf("successfully readKv2Secret secret from vault but no private key data avaialble for domain: %s", domain)
+		return nil, fmt.Errorf("successfully read secret from vault but no private key data avaialble for domain: %s", domain)
 	}
 
 	privRaw := fmt.Sprintf("%s", data["private_key"])
@@ -109,23 +113,48 @@ func (vault *VaultBackend) WriteAccount(acmeRegistration certstorage.AcmeAccount
 	key, _ := certstorage.ConvertToPem(acmeRegistration.Key)
 
 	data := map[string]interface{}{
-		certstorage.VaultAccountKeyUri:     acmeRegistration.Registration.URI,
-		certstorage.VaultAccountKeyAccount: jsonBytes,
-		certstorage.VaultAccountKeyKey:     key,
-		certstorage.VaultAccountKeyEmail:   acmeRegistration.Email,
+		certstorage.VaultAccountKeyUri:       acmeRegistration.Registration.URI,
+		certstorage.VaultAccountKeyAccount:   jsonBytes,
+		certstorage.VaultAccountKeyKey:       key,
+		certstorage.VaultAccountKeyEmail:     acmeRegistration.Email,
+		certstorage.VaultAccountKeyDirectory: acmeRegistration.Directory,
+	}
+
+	if len(acmeRegistration.EabKeyId) > 0 {
+		data[certstorage.VaultAccountKeyEabKeyId] = acmeRegistration.EabKeyId
 	}
 
-	accountPath := vault.getAccountPath(acmeRegistration.Email)
+	accountPath := vault.getAccountPath(acmeRegistration.Directory, acmeRegistration.Email)
 
-	err = vault.writeKv2Secret(accountPath, data)
+	err = vault.writeKvSecret(accountPath, data)
 	return err
 }
 
-func (vault *VaultBackend) ReadAccount(hash string) (*certstorage.AcmeAccount, error) {
-	accountPath := vault.getAccountPath(hash)
-	data, err := vault.readKv2Secret(accountPath)
+func (vault *VaultBackend) ReadAccount(directory, email string) (*certstorage.AcmeAccount, error) {
+	account, err := vault.readAccount(vault.getAccountPath(directory, email))
+	if err == nil || !errors.Is(err, certstorage.ErrNotFound) {
+		return account, err
+	}
+
+	// accounts used to be stored without distinguishing the CA. Only use such an account if it has been registered
+	// at the requested CA.
+	account, legacyErr := vault.readAccount(vault.getLegacyAccountPath(email))
+	if legacyErr != nil {
+		return nil, err
+	}
+
+	if config.AcmeDirectoryHost(account.Registration.URI) != config.AcmeDirectoryHost(directory) {
+		return nil, err
+	}
+
+	account.Directory = directory
+	return account, nil
+}
+
+func (vault *VaultBackend) readAccount(accountPath string) (*certstorage.AcmeAccount, error) {
+	data, err := vault.readKvSecret(accountPath)
 	if err != nil {
-		return nil, fmt.Errorf("could not readKv2Secret account from vault: %w", translateError(err))
+		return nil, fmt.Errorf("could not read account from vault: %w", translateError(err))
 	}
 
 	var account acme.Account
@@ -154,6 +183,16 @@ func (vault *VaultBackend) ReadAccount(hash string) (*certstorage.AcmeAccount, e
 		Registration: registration,
 	}
 
+	directory, ok := data[certstorage.VaultAccountKeyDirectory].(string)
+	if ok {
+		conf.Directory = directory
+	}
+
+	eabKeyId, ok := data[certstorage.VaultAccountKeyEabKeyId].(string)
+	if ok {
+		conf.EabKeyId = eabKeyId
+	}
+
 	return &conf, nil
 }
 
@@ -162,12 +201,25 @@ func (vault *VaultBackend) Authenticate() error {
 }
 
 func (vault *VaultBackend) Logout() error {
+	// the token is owned by the agent
+	if vault.conf.UseAgent() {
+		return nil
+	}
+
 	return vault.client.Auth().Token().RevokeSelf("xxx")
 }
 
 func (vault *VaultBackend) ReadAwsCredentials() (aws.Credentials, error) {
+	return vault.ReadAwsCredentialsForRole(vault.conf.AwsMountPath, vault.conf.AwsRole)
+}
+
+func (vault *VaultBackend) ReadAwsCredentialsForRole(mountPath, role string) (aws.Credentials, error) {
+	if len(mountPath) == 0 {
+		mountPath = vault.conf.AwsMountPath
+	}
+
 	metrics.AwsDynCredentialsRequested.Inc()
-	path := vault.getAwsCredentialsPath()
+	path := getAwsCredentialsPath(mountPath, role)
 	secret, err := vault.client.Logical().Read(path)
 	if err != nil {
 		metrics.AwsDynCredentialsRequestErrors.Inc()
@@ -177,26 +229,46 @@ func (vault *VaultBackend) ReadAwsCredentials() (aws.Credentials, error) {
 	return mapVaultAwsCredentialResponse(secret)
 }
 
-func (vault *VaultBackend) writeKv2Secret(secretPath string, data map[string]interface{}) error {
+func (vault *VaultBackend) ReadSecret(path string) (map[string]interface{}, error) {
+	data, err := vault.readKvSecret(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read secret from vault: %w", err)
+	}
+
+	return data, nil
+}
+
+func (vault *VaultBackend) writeKvSecret(secretPath string, data map[string]interface{}) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	_, err := vault.client.KVv2(vault.conf.Kv2MountPath).Put(ctx, secretPath, data)
-	return err
+
+	kv, err := vault.kv(ctx)
+	if err != nil {
+		return err
+	}
+
+	return translateError(kv.Put(ctx, secretPath, data))
 }
 
-func (vault *VaultBackend) readKv2Secret(path string) (map[string]interface{}, error) {
+func (vault *VaultBackend) readKvSecret(path string) (map[string]interface{}, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	secret, err := vault.client.KVv2(vault.conf.Kv2MountPath).Get(ctx, path)
+
+	kv, err := vault.kv(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := kv.Get(ctx, path)
 	if err != nil {
 		return nil, translateError(err)
 	}
 
-	if secret == nil {
+	if data == nil {
 		return nil, certstorage.ErrNotFound
 	}
 
-	return secret.Data, nil
+	return data, nil
 }
 
 func translateError(err error) error {
@@ -208,8 +280,8 @@ func translateError(err error) error {
 		return certstorage.ErrNotFound
 	}
 
-	vaultErr, ok := err.(*vault.ResponseError)
-	if !ok {
+	var vaultErr *vault.ResponseError
+	if !errors.As(err, &vaultErr) {
 		return err
 	}
 
@@ -231,12 +303,16 @@ func (vault *VaultBackend) formatDomain(domain string) string {
 	return fmt.Sprintf(vault.conf.DomainPathFormat, domain)
 }
 
-func (vault *VaultBackend) getAwsCredentialsPath() string {
-	return fmt.Sprintf("%s/creds/%s", vault.conf.AwsMountPath, vault.conf.AwsRole)
+func getAwsCredentialsPath(mountPath, role string) string {
+	return fmt.Sprintf("%s/creds/%s", mountPath, role)
+}
+
+func (vault *VaultBackend) getAccountPath(directory, email string) string {
+	return fmt.Sprintf("%s/server/account/%s/%s", vault.basePath, config.AcmeDirectoryHost(directory), email)
 }
 
-func (vault *VaultBackend) getAccountPath(hash string) string {
-	return fmt.Sprintf("%s/server/account/%s", vault.basePath, hash)
+func (vault *VaultBackend) getLegacyAccountPath(email string) string {
+	return fmt.Sprintf("%s/server/account/%s", vault.basePath, email)
 }
 
 func (vault *VaultBackend) getCertDataPath(domain string) string {
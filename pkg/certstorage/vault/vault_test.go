@@ -56,6 +56,31 @@ func TestVaultBackend_getSecretDataPath(t *testing.T) {
 	}
 }
 
+func TestVaultBackend_getAccountPath(t *testing.T) {
+	vault := &VaultBackend{basePath: "acmevault/prod"}
+
+	tests := []struct {
+		directory string
+		want      string
+	}{
+		{
+			directory: "https://acme-v02.api.letsencrypt.org/directory",
+			want:      "acmevault/prod/server/account/acme-v02.api.letsencrypt.org/my@email.tld",
+		},
+		{
+			directory: "https://acme.zerossl.com/v2/DV90",
+			want:      "acmevault/prod/server/account/acme.zerossl.com/my@email.tld",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.directory, func(t *testing.T) {
+			if got := vault.getAccountPath(tt.directory, "my@email.tld"); got != tt.want {
+				t.Errorf("getAccountPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestVaultBackend_writeKv2Secret(t *testing.T) {
 
 }